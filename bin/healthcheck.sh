@@ -3,6 +3,7 @@
 set -euxo "pipefail"
 IFS=$'\n\t'
 
-curl \
-    --fail \
-    "curl localhost:5003/time/"
+DIR="$( cd "$( dirname "${BASH_SOURCE[0]}" )" >/dev/null 2>&1 && pwd )"
+cd "$DIR/.." || exit 1
+
+./reaction-pics healthcheck
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// command is a subcommand of the reaction-pics binary
+type command struct {
+	name        string
+	description string
+	run         func(args []string, stdout, stderr io.Writer) int
+}
+
+// defaultCommand is run when no subcommand is given
+const defaultCommand = "serve"
+
+func commands() []command {
+	return []command{
+		{"serve", "run the web server", serveCommand},
+		{"import", "import posts from another source", importCommand},
+		{"export", "write saved posts as CSV or JSON", exportCommand},
+		{"validate", "check saved posts for data problems", validateCommand},
+		{"stats", "print statistics about saved posts", statsCommand},
+		{"search", "search saved posts", searchCommand},
+		{"index", "index image metadata of saved posts", indexCommand},
+		{"healthcheck", "check that a running server is healthy", healthcheckCommand},
+	}
+}
+
+// usage writes the list of subcommands
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: reaction-pics <command> [flags]")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "commands:")
+	for _, c := range commands() {
+		fmt.Fprintf(w, "  %-12s %s\n", c.name, c.description)
+	}
+}
+
+// runCommand runs the subcommand named by the first argument and returns an
+// exit code
+func runCommand(args []string, stdout, stderr io.Writer) int {
+	name := defaultCommand
+	if len(args) > 0 {
+		name = args[0]
+		args = args[1:]
+	}
+	if name == "help" || name == "-h" || name == "--help" {
+		usage(stdout)
+		return 0
+	}
+	for _, c := range commands() {
+		if c.name == name {
+			return c.run(args, stdout, stderr)
+		}
+	}
+	fmt.Fprintf(stderr, "unknown command: %s\n", name)
+	usage(stderr)
+	return 2
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUsage(t *testing.T) {
+	stdout := bytes.Buffer{}
+	code := runCommand([]string{"help"}, &stdout, &bytes.Buffer{})
+	assert.Equal(t, code, 0)
+	for _, c := range commands() {
+		assert.Contains(t, stdout.String(), c.name)
+	}
+}
+
+func TestRunCommandUnknown(t *testing.T) {
+	stderr := bytes.Buffer{}
+	code := runCommand([]string{"asdf"}, &bytes.Buffer{}, &stderr)
+	assert.Equal(t, code, 2)
+	assert.Contains(t, stderr.String(), "unknown command: asdf")
+}
+
+func TestRunCommand(t *testing.T) {
+	stdout := bytes.Buffer{}
+	code := runCommand([]string{"validate", "-posts", "tumblr/data/posts_test.csv"}, &stdout, &bytes.Buffer{})
+	assert.Equal(t, code, 0)
+	assert.Equal(t, stdout.String(), "1 posts, 0 problems\n")
+}
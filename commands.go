@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/albertyw/reaction-pics/server"
+	"github.com/albertyw/reaction-pics/tumblr"
+)
+
+const healthcheckTimeout = 3 * time.Second
+
+// newFlagSet returns a flag set for a subcommand that reports errors to stderr
+func newFlagSet(name, arguments string, stderr io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "usage: reaction-pics %s [flags] %s\n", name, arguments)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// serveCommand runs the web server with logging and error reporting
+func serveCommand(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("serve", "", stderr)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	setupRollbar()
+	logger := getLogger()
+	defer logger.Sync()
+	newrelicApp := getNewRelicApp(logger)
+	server.Run(newrelicApp, logger)
+	return 0
+}
+
+// exportCommand writes saved posts to stdout
+func exportCommand(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("export", "", stderr)
+	format := flags.String("format", "csv", "output format: csv or json")
+	csvPath := flags.String("posts", tumblr.SavedPostsPath(), "CSV file of saved posts")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	posts := tumblr.ReadPostsFromCSV(*csvPath)
+	var err error
+	switch *format {
+	case "csv":
+		err = tumblr.WritePosts(stdout, posts)
+	case "json":
+		board := tumblr.NewBoard(posts)
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(board.PostsToJSON())
+	default:
+		fmt.Fprintf(stderr, "unknown export format: %s\n", *format)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// validateCommand reports problems with saved posts
+func validateCommand(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("validate", "", stderr)
+	csvPath := flags.String("posts", tumblr.SavedPostsPath(), "CSV file of saved posts")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	posts := tumblr.ReadPostsFromCSV(*csvPath)
+	problems := tumblr.ValidatePosts(posts)
+	for _, problem := range problems {
+		fmt.Fprintln(stdout, problem)
+	}
+	fmt.Fprintf(stdout, "%d posts, %d problems\n", len(posts), len(problems))
+	if len(problems) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// statsCommand prints statistics about saved posts
+func statsCommand(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("stats", "", stderr)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	board := tumblr.LoadBoard()
+	static := board.FilterBoardByImage(tumblr.ImageFilter{Type: tumblr.ImageTypeStatic})
+	animated := board.FilterBoardByImage(tumblr.ImageFilter{Type: tumblr.ImageTypeAnimated})
+	fmt.Fprintf(stdout, "Posts: %d\n", len(board.Posts))
+	fmt.Fprintf(stdout, "Static images: %d\n", len(static.Posts))
+	fmt.Fprintf(stdout, "Animated images: %d\n", len(animated.Posts))
+	fmt.Fprintf(stdout, "Unindexed images: %d\n", len(board.Posts)-len(static.Posts)-len(animated.Posts))
+	fmt.Fprintf(stdout, "Keywords: %s\n", strings.Join(board.Keywords(), ", "))
+	return 0
+}
+
+// indexCommand indexes the metadata of images in a local copy of the image store
+func indexCommand(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("index", "<image dir>", stderr)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	err := tumblr.IndexImagesToCSV(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// healthcheckCommand checks that a running server responds successfully
+func healthcheckCommand(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("healthcheck", "", stderr)
+	defaultURL := fmt.Sprintf("http://localhost:%s/time/", os.Getenv("PORT"))
+	url := flags.String("url", defaultURL, "url to check")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	client := http.Client{Timeout: healthcheckTimeout}
+	response, err := client.Get(*url)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		fmt.Fprintf(stderr, "unhealthy status: %d\n", response.StatusCode)
+		return 1
+	}
+	fmt.Fprintln(stdout, "ok")
+	return 0
+}
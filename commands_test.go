@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testPostsPath = "tumblr/data/posts_test.csv"
+
+func TestExportCommandCSV(t *testing.T) {
+	stdout := bytes.Buffer{}
+	code := exportCommand([]string{"-posts", testPostsPath}, &stdout, &bytes.Buffer{})
+	assert.Equal(t, code, 0)
+	data, err := ioutil.ReadFile(testPostsPath)
+	assert.NoError(t, err)
+	assert.Equal(t, stdout.String(), string(data))
+}
+
+func TestExportCommandJSON(t *testing.T) {
+	stdout := bytes.Buffer{}
+	code := exportCommand([]string{"-posts", testPostsPath, "-format", "json"}, &stdout, &bytes.Buffer{})
+	assert.Equal(t, code, 0)
+	var posts []map[string]interface{}
+	err := json.Unmarshal(stdout.Bytes(), &posts)
+	assert.NoError(t, err)
+	assert.Equal(t, posts[0]["internalURL"], "/post/1234/title")
+
+	code = exportCommand([]string{"-format", "asdf"}, &stdout, &bytes.Buffer{})
+	assert.Equal(t, code, 2)
+}
+
+func TestValidateCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "validate")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	csvPath := filepath.Join(dir, "posts.csv")
+	err = ioutil.WriteFile(csvPath, []byte("1,title,url,a.gif,1\n1,title,url,b.gif,1\n"), 0644)
+	assert.NoError(t, err)
+
+	stdout := bytes.Buffer{}
+	code := validateCommand([]string{"-posts", csvPath}, &stdout, &bytes.Buffer{})
+	assert.Equal(t, code, 1)
+	assert.Contains(t, stdout.String(), "1: duplicate id")
+	assert.Contains(t, stdout.String(), "2 posts, 2 problems")
+}
+
+func TestStatsCommand(t *testing.T) {
+	stdout := bytes.Buffer{}
+	code := statsCommand([]string{}, &stdout, &bytes.Buffer{})
+	assert.Equal(t, code, 0)
+	assert.Contains(t, stdout.String(), "Posts: ")
+	assert.Contains(t, stdout.String(), "Keywords: ")
+}
+
+func TestIndexCommand(t *testing.T) {
+	code := indexCommand([]string{}, &bytes.Buffer{}, &bytes.Buffer{})
+	assert.Equal(t, code, 2)
+}
+
+func TestHealthcheckCommand(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer healthy.Close()
+	unhealthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer unhealthy.Close()
+
+	code := healthcheckCommand([]string{"-url", healthy.URL}, &bytes.Buffer{}, &bytes.Buffer{})
+	assert.Equal(t, code, 0)
+	code = healthcheckCommand([]string{"-url", unhealthy.URL}, &bytes.Buffer{}, &bytes.Buffer{})
+	assert.Equal(t, code, 1)
+}
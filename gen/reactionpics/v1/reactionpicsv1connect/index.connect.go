@@ -0,0 +1,147 @@
+// Code generated by protoc-gen-connect-go. DO NOT EDIT.
+//
+// Source: reactionpics/v1/index.proto
+
+package reactionpicsv1connect
+
+import (
+	connect "connectrpc.com/connect"
+	context "context"
+	errors "errors"
+	v1 "github.com/albertyw/reaction-pics/gen/reactionpics/v1"
+	http "net/http"
+	strings "strings"
+)
+
+// This is a compile-time assertion to ensure that this generated file and the connect package are
+// compatible. If you get a compiler error that this constant is not defined, this code was
+// generated with a version of connect newer than the one compiled into your binary. You can fix the
+// problem by either regenerating this code with an older version of connect or updating the connect
+// version compiled into your binary.
+const _ = connect.IsAtLeastVersion1_13_0
+
+const (
+	// IndexServiceName is the fully-qualified name of the IndexService service.
+	IndexServiceName = "reactionpics.v1.IndexService"
+)
+
+// These constants are the fully-qualified names of the RPCs defined in this package. They're
+// exposed at runtime as Spec.Procedure and as the final two segments of the HTTP route.
+//
+// Note that these are different from the fully-qualified method names used by
+// google.golang.org/protobuf/reflect/protoreflect. To convert from these constants to
+// reflection-formatted method names, remove the leading slash and convert the remaining slash to a
+// period.
+const (
+	// IndexServiceSearchProcedure is the fully-qualified name of the IndexService's Search RPC.
+	IndexServiceSearchProcedure = "/reactionpics.v1.IndexService/Search"
+	// IndexServiceGetPostProcedure is the fully-qualified name of the IndexService's GetPost RPC.
+	IndexServiceGetPostProcedure = "/reactionpics.v1.IndexService/GetPost"
+)
+
+// These variables are the protoreflect.Descriptor objects for the RPCs defined in this package.
+var (
+	indexServiceServiceDescriptor       = v1.File_reactionpics_v1_index_proto.Services().ByName("IndexService")
+	indexServiceSearchMethodDescriptor  = indexServiceServiceDescriptor.Methods().ByName("Search")
+	indexServiceGetPostMethodDescriptor = indexServiceServiceDescriptor.Methods().ByName("GetPost")
+)
+
+// IndexServiceClient is a client for the reactionpics.v1.IndexService service.
+type IndexServiceClient interface {
+	// Search returns a page of posts matching a query, ranked by likes. An
+	// empty query returns random posts.
+	Search(context.Context, *connect.Request[v1.SearchRequest]) (*connect.Response[v1.SearchResponse], error)
+	// GetPost returns a single post by id
+	GetPost(context.Context, *connect.Request[v1.GetPostRequest]) (*connect.Response[v1.GetPostResponse], error)
+}
+
+// NewIndexServiceClient constructs a client for the reactionpics.v1.IndexService service. By
+// default, it uses the Connect protocol with the binary Protobuf Codec, asks for gzipped responses,
+// and sends uncompressed requests. To use the gRPC or gRPC-Web protocols, supply the
+// connect.WithGRPC() or connect.WithGRPCWeb() options.
+//
+// The URL supplied here should be the base URL for the Connect or gRPC server (for example,
+// http://api.acme.com or https://acme.com/grpc).
+func NewIndexServiceClient(httpClient connect.HTTPClient, baseURL string, opts ...connect.ClientOption) IndexServiceClient {
+	baseURL = strings.TrimRight(baseURL, "/")
+	return &indexServiceClient{
+		search: connect.NewClient[v1.SearchRequest, v1.SearchResponse](
+			httpClient,
+			baseURL+IndexServiceSearchProcedure,
+			connect.WithSchema(indexServiceSearchMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		getPost: connect.NewClient[v1.GetPostRequest, v1.GetPostResponse](
+			httpClient,
+			baseURL+IndexServiceGetPostProcedure,
+			connect.WithSchema(indexServiceGetPostMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+	}
+}
+
+// indexServiceClient implements IndexServiceClient.
+type indexServiceClient struct {
+	search  *connect.Client[v1.SearchRequest, v1.SearchResponse]
+	getPost *connect.Client[v1.GetPostRequest, v1.GetPostResponse]
+}
+
+// Search calls reactionpics.v1.IndexService.Search.
+func (c *indexServiceClient) Search(ctx context.Context, req *connect.Request[v1.SearchRequest]) (*connect.Response[v1.SearchResponse], error) {
+	return c.search.CallUnary(ctx, req)
+}
+
+// GetPost calls reactionpics.v1.IndexService.GetPost.
+func (c *indexServiceClient) GetPost(ctx context.Context, req *connect.Request[v1.GetPostRequest]) (*connect.Response[v1.GetPostResponse], error) {
+	return c.getPost.CallUnary(ctx, req)
+}
+
+// IndexServiceHandler is an implementation of the reactionpics.v1.IndexService service.
+type IndexServiceHandler interface {
+	// Search returns a page of posts matching a query, ranked by likes. An
+	// empty query returns random posts.
+	Search(context.Context, *connect.Request[v1.SearchRequest]) (*connect.Response[v1.SearchResponse], error)
+	// GetPost returns a single post by id
+	GetPost(context.Context, *connect.Request[v1.GetPostRequest]) (*connect.Response[v1.GetPostResponse], error)
+}
+
+// NewIndexServiceHandler builds an HTTP handler from the service implementation. It returns the
+// path on which to mount the handler and the handler itself.
+//
+// By default, handlers support the Connect, gRPC, and gRPC-Web protocols with the binary Protobuf
+// and JSON codecs. They also support gzip compression.
+func NewIndexServiceHandler(svc IndexServiceHandler, opts ...connect.HandlerOption) (string, http.Handler) {
+	indexServiceSearchHandler := connect.NewUnaryHandler(
+		IndexServiceSearchProcedure,
+		svc.Search,
+		connect.WithSchema(indexServiceSearchMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	indexServiceGetPostHandler := connect.NewUnaryHandler(
+		IndexServiceGetPostProcedure,
+		svc.GetPost,
+		connect.WithSchema(indexServiceGetPostMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	return "/reactionpics.v1.IndexService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case IndexServiceSearchProcedure:
+			indexServiceSearchHandler.ServeHTTP(w, r)
+		case IndexServiceGetPostProcedure:
+			indexServiceGetPostHandler.ServeHTTP(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// UnimplementedIndexServiceHandler returns CodeUnimplemented from all methods.
+type UnimplementedIndexServiceHandler struct{}
+
+func (UnimplementedIndexServiceHandler) Search(context.Context, *connect.Request[v1.SearchRequest]) (*connect.Response[v1.SearchResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("reactionpics.v1.IndexService.Search is not implemented"))
+}
+
+func (UnimplementedIndexServiceHandler) GetPost(context.Context, *connect.Request[v1.GetPostRequest]) (*connect.Response[v1.GetPostResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("reactionpics.v1.IndexService.GetPost is not implemented"))
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/albertyw/reaction-pics/importer"
+	"github.com/albertyw/reaction-pics/tumblr"
+)
+
+// newImporter returns the importer for a source format
+func newImporter(format, path, imageDir string) (importer.Importer, error) {
+	switch format {
+	case "json":
+		return importer.JSONImporter{Path: path}, nil
+	case "dir":
+		return importer.DirImporter{Dir: path, ImageDir: imageDir}, nil
+	case "reddit":
+		return importer.RedditImporter{Path: path}, nil
+	case "tumblr":
+		return importer.TumblrArchiveImporter{Path: path, ImageDir: imageDir}, nil
+	}
+	return nil, fmt.Errorf("unknown import format: %s", format)
+}
+
+// importCommand imports posts from another source into the saved posts
+func importCommand(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("import", "<source>", stderr)
+	format := flags.String("format", "json", "source format: json, dir, reddit, or tumblr (blog export zip or legacy posts.xml backup)")
+	imageDir := flags.String("images", "", "image store directory to copy images into")
+	dryRun := flags.Bool("dry-run", false, "print changes without saving them")
+	csvPath := flags.String("posts", tumblr.SavedPostsPath(), "CSV file of saved posts")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	i, err := newImporter(*format, flags.Arg(0), *imageDir)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	_, err = importer.Run(i, *csvPath, *dryRun, stdout)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewImporter(t *testing.T) {
+	for _, format := range []string{"json", "dir", "reddit", "tumblr"} {
+		i, err := newImporter(format, "path", "")
+		assert.NoError(t, err)
+		assert.Equal(t, i.Name(), format)
+	}
+	_, err := newImporter("asdf", "path", "")
+	assert.Error(t, err)
+}
+
+func TestImportCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "import")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	csvPath := filepath.Join(dir, "posts.csv")
+
+	stdout := bytes.Buffer{}
+	stderr := bytes.Buffer{}
+	args := []string{"-posts", csvPath, "-dry-run", "importer/testdata/posts.json"}
+	code := importCommand(args, &stdout, &stderr)
+	assert.Equal(t, code, 0)
+	assert.Contains(t, stdout.String(), "2 added, 0 updated")
+	_, err = os.Stat(csvPath)
+	assert.True(t, os.IsNotExist(err))
+
+	code = importCommand([]string{"-posts", csvPath, "missing.json"}, &stdout, &stderr)
+	assert.Equal(t, code, 1)
+	code = importCommand([]string{"-format", "asdf", "path"}, &stdout, &stderr)
+	assert.Equal(t, code, 2)
+	code = importCommand([]string{}, &stdout, &stderr)
+	assert.Equal(t, code, 2)
+}
@@ -0,0 +1,163 @@
+package importer
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/albertyw/reaction-pics/tumblr"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	imageExtensions   = []string{".gif", ".jpg", ".jpeg", ".png"}
+	sidecarExtensions = []string{".yaml", ".yml"}
+)
+
+// sidecar is the YAML metadata describing an image
+type sidecar struct {
+	ID        int64    `yaml:"id"`
+	Title     string   `yaml:"title"`
+	URL       string   `yaml:"url"`
+	Likes     int64    `yaml:"likes"`
+	Tags      []string `yaml:"tags"`
+	Timestamp int64    `yaml:"timestamp"`
+}
+
+// DirImporter imports a directory of images, each optionally described by a
+// YAML sidecar file with the same base name.  Images without a sidecar are
+// titled after their file name.  If ImageDir is set, the images are copied
+// into the image store, which fails rather than replacing a different image
+// with the same name.
+type DirImporter struct {
+	Dir      string
+	ImageDir string
+}
+
+// Name returns the name of the source
+func (i DirImporter) Name() string {
+	return "dir"
+}
+
+// Import reads posts from the directory
+func (i DirImporter) Import() ([]tumblr.Post, error) {
+	files, err := ioutil.ReadDir(i.Dir)
+	if err != nil {
+		return nil, err
+	}
+	posts := []tumblr.Post{}
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !hasExtension(name, imageExtensions) {
+			continue
+		}
+		base := strings.TrimSuffix(name, filepath.Ext(name))
+		meta, err := i.readSidecar(base)
+		if err != nil {
+			return nil, err
+		}
+		if meta.Title == "" {
+			meta.Title = strings.NewReplacer("-", " ", "_", " ").Replace(base)
+		}
+		posts = append(posts, tumblr.Post{
+			ID:        meta.ID,
+			Title:     meta.Title,
+			URL:       meta.URL,
+			Image:     tumblr.ImageURL(name),
+			Likes:     meta.Likes,
+			Tags:      meta.Tags,
+			Timestamp: meta.Timestamp,
+		})
+	}
+	return posts, nil
+}
+
+// CopyMedia copies the images of posts from the directory into the image
+// store
+func (i DirImporter) CopyMedia(posts []tumblr.Post) error {
+	if i.ImageDir == "" {
+		return nil
+	}
+	for _, post := range posts {
+		name := strings.TrimPrefix(post.Image, tumblr.ImageURL(""))
+		if name == post.Image {
+			continue
+		}
+		err := copyFile(filepath.Join(i.Dir, name), filepath.Join(i.ImageDir, name))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readSidecar reads the sidecar of an image, returning empty metadata if
+// there is none
+func (i DirImporter) readSidecar(base string) (sidecar, error) {
+	meta := sidecar{}
+	for _, ext := range sidecarExtensions {
+		data, err := ioutil.ReadFile(filepath.Join(i.Dir, base+ext))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return meta, err
+		}
+		err = yaml.Unmarshal(data, &meta)
+		return meta, errors.Wrapf(err, "Cannot parse sidecar for %s", base)
+	}
+	return meta, nil
+}
+
+func hasExtension(name string, extensions []string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range extensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// copyFile copies a file without overwriting an existing destination.  An
+// existing destination with the same contents is left as is, and one with
+// other contents is reported as an error since it is another image.
+func copyFile(source, destination string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(destination, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		return sameFile(source, destination)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// sameFile returns an error if two files have different contents
+func sameFile(source, destination string) error {
+	sourceData, err := ioutil.ReadFile(source)
+	if err != nil {
+		return err
+	}
+	destinationData, err := ioutil.ReadFile(destination)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(sourceData, destinationData) {
+		return errors.Errorf("A different image named %s is already in the image store", filepath.Base(destination))
+	}
+	return nil
+}
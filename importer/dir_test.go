@@ -0,0 +1,70 @@
+package importer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/albertyw/reaction-pics/tumblr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDirImporter(t *testing.T) {
+	imageDir, err := ioutil.TempDir("", "images")
+	assert.NoError(t, err)
+	defer os.RemoveAll(imageDir)
+
+	i := DirImporter{Dir: "testdata/dir", ImageDir: imageDir}
+	assert.Equal(t, i.Name(), "dir")
+	posts, err := i.Import()
+	assert.NoError(t, err)
+	assert.Equal(t, len(posts), 2)
+	assert.Equal(t, posts[0].Title, "on call pager")
+	assert.Equal(t, posts[0].Image, tumblr.ImageURL("on-call-pager.gif"))
+	assert.Equal(t, posts[1].Title, "When the outage is DNS")
+	assert.Equal(t, posts[1].URL, "https://example.com/outage")
+	assert.Equal(t, posts[1].Likes, int64(40))
+	assert.Equal(t, posts[1].Tags, []string{"dns", "outage"})
+	assert.Equal(t, posts[1].Timestamp, int64(1500000000))
+
+	files, err := ioutil.ReadDir(imageDir)
+	assert.NoError(t, err)
+	assert.Equal(t, len(files), 0)
+
+	err = i.CopyMedia(posts[1:])
+	assert.NoError(t, err)
+	_, err = os.Stat(filepath.Join(imageDir, "outage.gif"))
+	assert.NoError(t, err)
+	_, err = os.Stat(filepath.Join(imageDir, "on-call-pager.gif"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestDirImporterMissing(t *testing.T) {
+	i := DirImporter{Dir: "testdata/missing"}
+	_, err := i.Import()
+	assert.Error(t, err)
+}
+
+func TestDirImporterCopyMediaExisting(t *testing.T) {
+	imageDir, err := ioutil.TempDir("", "images")
+	assert.NoError(t, err)
+	defer os.RemoveAll(imageDir)
+	i := DirImporter{Dir: "testdata/dir", ImageDir: imageDir}
+	posts, err := i.Import()
+	assert.NoError(t, err)
+
+	data, err := ioutil.ReadFile("testdata/dir/outage.gif")
+	assert.NoError(t, err)
+	err = ioutil.WriteFile(filepath.Join(imageDir, "outage.gif"), data, 0644)
+	assert.NoError(t, err)
+	assert.NoError(t, i.CopyMedia(posts))
+
+	err = ioutil.WriteFile(filepath.Join(imageDir, "outage.gif"), []byte("existing"), 0644)
+	assert.NoError(t, err)
+	err = i.CopyMedia(posts)
+	assert.Error(t, err)
+	data, err = ioutil.ReadFile(filepath.Join(imageDir, "outage.gif"))
+	assert.NoError(t, err)
+	assert.Equal(t, string(data), "existing")
+}
@@ -0,0 +1,144 @@
+package importer
+
+import (
+	"fmt"
+	"hash/fnv"
+	"html"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/albertyw/reaction-pics/tumblr"
+	"github.com/pkg/errors"
+)
+
+// sourceTagPrefix is prepended to the importer name to tag imported posts
+const sourceTagPrefix = "source:"
+
+// Importer reads reactions from an external source
+type Importer interface {
+	// Name is a short identifier of the source, used for tagging posts
+	Name() string
+	// Import reads all posts from the source
+	Import() ([]tumblr.Post, error)
+}
+
+// MediaImporter is an Importer whose posts have media that is copied into
+// the image store when an import is saved
+type MediaImporter interface {
+	Importer
+	// CopyMedia copies the media of imported posts into the image store
+	CopyMedia(posts []tumblr.Post) error
+}
+
+// Change is the difference that importing makes to the saved posts
+type Change struct {
+	Added   []tumblr.Post
+	Updated []tumblr.Post
+}
+
+// Empty returns whether importing changes nothing
+func (c Change) Empty() bool {
+	return len(c.Added) == 0 && len(c.Updated) == 0
+}
+
+// Print writes a human readable summary of the change
+func (c Change) Print(w io.Writer) {
+	for _, post := range c.Added {
+		fmt.Fprintf(w, "+ %d %s (%s)\n", post.ID, post.Title, post.Image)
+	}
+	for _, post := range c.Updated {
+		fmt.Fprintf(w, "~ %d %s\n", post.ID, post.Title)
+	}
+	fmt.Fprintf(w, "%d added, %d updated\n", len(c.Added), len(c.Updated))
+}
+
+// CleanTitle unescapes html entities and collapses whitespace in a title
+func CleanTitle(title string) string {
+	title = html.UnescapeString(title)
+	return strings.Join(strings.Fields(title), " ")
+}
+
+// PostID deterministically derives an ID for a post from a source that does
+// not have tumblr IDs, so that importing the same source twice deduplicates
+func PostID(source, key string) int64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(source + "\x00" + key))
+	id := int64(hash.Sum64() >> 1)
+	if id == 0 {
+		id = 1
+	}
+	return id
+}
+
+// Normalize cleans up the titles of posts, assigns IDs to posts without one,
+// and tags posts with the name of their source.  Posts without a title or an
+// image are dropped.
+func Normalize(source string, posts []tumblr.Post) []tumblr.Post {
+	sourceTag := sourceTagPrefix + source
+	normalized := []tumblr.Post{}
+	for _, post := range posts {
+		post.Title = CleanTitle(post.Title)
+		if post.Title == "" || post.Image == "" {
+			continue
+		}
+		if post.ID == 0 {
+			post.ID = PostID(source, post.Image)
+		}
+		hasSourceTag := false
+		tags := []string{}
+		for _, tag := range post.Tags {
+			tag = strings.TrimSpace(tag)
+			if tag == "" {
+				continue
+			}
+			hasSourceTag = hasSourceTag || tag == sourceTag
+			tags = append(tags, tag)
+		}
+		if !hasSourceTag {
+			tags = append(tags, sourceTag)
+		}
+		post.Tags = tags
+		normalized = append(normalized, post)
+	}
+	return normalized
+}
+
+// Plan returns the merged posts and the change from importing posts into
+// existing posts
+func Plan(existing, posts []tumblr.Post) ([]tumblr.Post, Change) {
+	merged, _, _ := tumblr.MergePosts(existing, posts)
+	change := Change{}
+	for i, post := range merged {
+		if i >= len(existing) {
+			change.Added = append(change.Added, post)
+		} else if !reflect.DeepEqual(post, existing[i]) {
+			change.Updated = append(change.Updated, post)
+		}
+	}
+	return merged, change
+}
+
+// Run imports posts from an importer into the posts saved at csvPath.  The
+// media of added posts is copied before the posts are saved.  A dry run
+// prints the change without copying media or saving posts.
+func Run(i Importer, csvPath string, dryRun bool, out io.Writer) (Change, error) {
+	posts, err := i.Import()
+	if err != nil {
+		return Change{}, errors.Wrapf(err, "Cannot import from %s", i.Name())
+	}
+	posts = Normalize(i.Name(), posts)
+	merged, change := Plan(tumblr.ReadPostsFromCSV(csvPath), posts)
+	change.Print(out)
+	if dryRun || change.Empty() {
+		return change, nil
+	}
+	if m, ok := i.(MediaImporter); ok {
+		err = m.CopyMedia(change.Added)
+		if err != nil {
+			return change, errors.Wrapf(err, "Cannot copy media from %s", i.Name())
+		}
+	}
+	err = tumblr.WritePostsToCSV(csvPath, merged)
+	return change, err
+}
@@ -0,0 +1,119 @@
+package importer
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/albertyw/reaction-pics/tumblr"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeImporter struct {
+	posts []tumblr.Post
+}
+
+func (i fakeImporter) Name() string                   { return "fake" }
+func (i fakeImporter) Import() ([]tumblr.Post, error) { return i.posts, nil }
+
+func TestCleanTitle(t *testing.T) {
+	assert.Equal(t, CleanTitle("  a \n b&amp;c "), "a b&c")
+}
+
+func TestPostID(t *testing.T) {
+	id := PostID("source", "key")
+	assert.True(t, id > 0)
+	assert.Equal(t, id, PostID("source", "key"))
+	assert.NotEqual(t, id, PostID("other", "key"))
+}
+
+func TestNormalize(t *testing.T) {
+	posts := []tumblr.Post{
+		{Title: " title ", Image: "image", Tags: []string{" tag ", ""}},
+		{ID: 5, Title: "title", Image: "image", Tags: []string{"source:fake"}},
+		{Title: "no image"},
+		{Title: " ", Image: "no title"},
+	}
+	normalized := Normalize("fake", posts)
+	assert.Equal(t, len(normalized), 2)
+	assert.Equal(t, normalized[0].Title, "title")
+	assert.Equal(t, normalized[0].ID, PostID("fake", "image"))
+	assert.Equal(t, normalized[0].Tags, []string{"tag", "source:fake"})
+	assert.Equal(t, normalized[1].ID, int64(5))
+	assert.Equal(t, normalized[1].Tags, []string{"source:fake"})
+}
+
+func TestPlan(t *testing.T) {
+	existing := []tumblr.Post{
+		{ID: 1, Title: "title1"},
+		{ID: 2, Title: "title2"},
+	}
+	posts := []tumblr.Post{
+		{ID: 2, Title: "title2", Tags: []string{"tag"}},
+		{ID: 3, Title: "title3"},
+	}
+	merged, change := Plan(existing, posts)
+	assert.Equal(t, len(merged), 3)
+	assert.Equal(t, len(change.Added), 1)
+	assert.Equal(t, change.Added[0].ID, int64(3))
+	assert.Equal(t, len(change.Updated), 1)
+	assert.Equal(t, change.Updated[0].ID, int64(2))
+	assert.False(t, change.Empty())
+
+	buf := bytes.Buffer{}
+	change.Print(&buf)
+	assert.Equal(t, buf.String(), "+ 3 title3 ()\n~ 2 title2\n1 added, 1 updated\n")
+}
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "importer")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	csvPath := filepath.Join(dir, "posts.csv")
+	err = ioutil.WriteFile(csvPath, []byte("1,title1,url,image.gif,5\n"), 0644)
+	assert.NoError(t, err)
+	i := fakeImporter{posts: []tumblr.Post{{ID: 2, Title: "title2", Image: tumblr.ImageURL("new.gif")}}}
+
+	buf := bytes.Buffer{}
+	change, err := Run(i, csvPath, true, &buf)
+	assert.NoError(t, err)
+	assert.Equal(t, len(change.Added), 1)
+	assert.Contains(t, buf.String(), "1 added, 0 updated")
+	assert.Equal(t, len(tumblr.ReadPostsFromCSV(csvPath)), 1)
+
+	_, err = Run(i, csvPath, false, &buf)
+	assert.NoError(t, err)
+	posts := tumblr.ReadPostsFromCSV(csvPath)
+	assert.Equal(t, len(posts), 2)
+	assert.Equal(t, posts[1].Tags, []string{"source:fake"})
+
+	change, err = Run(i, csvPath, false, &buf)
+	assert.NoError(t, err)
+	assert.True(t, change.Empty())
+}
+
+func TestRunDryRunCopiesNoMedia(t *testing.T) {
+	dir, err := ioutil.TempDir("", "importer")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	csvPath := filepath.Join(dir, "posts.csv")
+	imageDir := filepath.Join(dir, "images")
+	assert.NoError(t, os.Mkdir(imageDir, 0755))
+	i := DirImporter{Dir: "testdata/dir", ImageDir: imageDir}
+
+	buf := bytes.Buffer{}
+	change, err := Run(i, csvPath, true, &buf)
+	assert.NoError(t, err)
+	assert.Equal(t, len(change.Added), 2)
+	files, err := ioutil.ReadDir(imageDir)
+	assert.NoError(t, err)
+	assert.Equal(t, len(files), 0)
+
+	_, err = Run(i, csvPath, false, &buf)
+	assert.NoError(t, err)
+	files, err = ioutil.ReadDir(imageDir)
+	assert.NoError(t, err)
+	assert.Equal(t, len(files), 2)
+}
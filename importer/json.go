@@ -0,0 +1,47 @@
+package importer
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/albertyw/reaction-pics/tumblr"
+)
+
+// JSONImporter imports a JSON list of posts with the same fields as the
+// /postdata API.  Images may either be urls or file names in the image store.
+type JSONImporter struct {
+	Path string
+}
+
+// Name returns the name of the source
+func (i JSONImporter) Name() string {
+	return "json"
+}
+
+// Import reads posts from the JSON file
+func (i JSONImporter) Import() ([]tumblr.Post, error) {
+	file, err := os.Open(i.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	posts := []tumblr.Post{}
+	err = json.NewDecoder(file).Decode(&posts)
+	if err != nil {
+		return nil, err
+	}
+	for j := range posts {
+		posts[j].Image = imageURL(posts[j].Image)
+	}
+	return posts, nil
+}
+
+// imageURL returns image unchanged if it is a url and otherwise the url of
+// the image in the image store
+func imageURL(image string) string {
+	if image == "" || strings.HasPrefix(image, "http://") || strings.HasPrefix(image, "https://") {
+		return image
+	}
+	return tumblr.ImageURL(image)
+}
@@ -0,0 +1,27 @@
+package importer
+
+import (
+	"testing"
+
+	"github.com/albertyw/reaction-pics/tumblr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONImporter(t *testing.T) {
+	i := JSONImporter{Path: "testdata/posts.json"}
+	assert.Equal(t, i.Name(), "json")
+	posts, err := i.Import()
+	assert.NoError(t, err)
+	assert.Equal(t, len(posts), 2)
+	assert.Equal(t, posts[0].ID, int64(1234))
+	assert.Equal(t, posts[0].Image, tumblr.ImageURL("abcd.gif"))
+	assert.Equal(t, posts[0].Likes, int64(12))
+	assert.Equal(t, posts[0].Tags, []string{"deploy"})
+	assert.Equal(t, posts[1].Image, "https://example.com/rollback.gif")
+}
+
+func TestJSONImporterMissing(t *testing.T) {
+	i := JSONImporter{Path: "testdata/missing.json"}
+	_, err := i.Import()
+	assert.Error(t, err)
+}
@@ -0,0 +1,85 @@
+package importer
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/albertyw/reaction-pics/tumblr"
+)
+
+const redditURL = "https://www.reddit.com"
+
+// redditListing is the JSON of a reddit listing page
+type redditListing struct {
+	Data struct {
+		Children []struct {
+			Kind string     `json:"kind"`
+			Data redditLink `json:"data"`
+		} `json:"children"`
+	} `json:"data"`
+}
+
+// redditLink is a reddit submission
+type redditLink struct {
+	Title      string  `json:"title"`
+	Permalink  string  `json:"permalink"`
+	URL        string  `json:"url"`
+	PostHint   string  `json:"post_hint"`
+	Score      int64   `json:"score"`
+	CreatedUTC float64 `json:"created_utc"`
+	Subreddit  string  `json:"subreddit"`
+	Flair      string  `json:"link_flair_text"`
+}
+
+// isImage returns whether the submission links directly to an image
+func (l redditLink) isImage() bool {
+	return l.PostHint == "image" || hasExtension(l.URL, imageExtensions)
+}
+
+// RedditImporter imports image submissions from a saved reddit JSON listing
+type RedditImporter struct {
+	Path string
+}
+
+// Name returns the name of the source
+func (i RedditImporter) Name() string {
+	return "reddit"
+}
+
+// Import reads posts from the listing
+func (i RedditImporter) Import() ([]tumblr.Post, error) {
+	file, err := os.Open(i.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	listing := redditListing{}
+	err = json.NewDecoder(file).Decode(&listing)
+	if err != nil {
+		return nil, err
+	}
+	posts := []tumblr.Post{}
+	for _, child := range listing.Data.Children {
+		link := child.Data
+		if child.Kind != "t3" || !link.isImage() {
+			continue
+		}
+		tags := []string{}
+		if link.Subreddit != "" {
+			tags = append(tags, "r/"+link.Subreddit)
+		}
+		if flair := strings.TrimSpace(link.Flair); flair != "" {
+			tags = append(tags, flair)
+		}
+		posts = append(posts, tumblr.Post{
+			Title:     link.Title,
+			URL:       redditURL + link.Permalink,
+			Image:     link.URL,
+			Likes:     link.Score,
+			Tags:      tags,
+			Timestamp: int64(link.CreatedUTC),
+		})
+	}
+	return posts, nil
+}
@@ -0,0 +1,22 @@
+package importer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedditImporter(t *testing.T) {
+	i := RedditImporter{Path: "testdata/reddit.json"}
+	assert.Equal(t, i.Name(), "reddit")
+	posts, err := i.Import()
+	assert.NoError(t, err)
+	assert.Equal(t, len(posts), 2)
+	assert.Equal(t, posts[0].Title, "MRW the build goes green")
+	assert.Equal(t, posts[0].URL, "https://www.reddit.com/r/ProgrammerHumor/comments/abc123/mrw/")
+	assert.Equal(t, posts[0].Image, "https://i.redd.it/abc123.gif")
+	assert.Equal(t, posts[0].Likes, int64(321))
+	assert.Equal(t, posts[0].Tags, []string{"r/ProgrammerHumor", "Meme"})
+	assert.Equal(t, posts[0].Timestamp, int64(1600000000))
+	assert.Equal(t, posts[1].Image, "https://imgur.com/ghi789")
+}
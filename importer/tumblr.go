@@ -0,0 +1,31 @@
+package importer
+
+import (
+	"github.com/albertyw/reaction-pics/tumblr"
+)
+
+// TumblrArchiveImporter imports a tumblr blog export, or a legacy posts.xml
+// backup, as read by tumblr.ReadArchive.  If ImageDir is set, the archived
+// media is copied into the image store.
+type TumblrArchiveImporter struct {
+	Path     string
+	ImageDir string
+}
+
+// Name returns the name of the source
+func (i TumblrArchiveImporter) Name() string {
+	return "tumblr"
+}
+
+// Import reads posts from the archive
+func (i TumblrArchiveImporter) Import() ([]tumblr.Post, error) {
+	return tumblr.ReadArchive(i.Path, i.ImageDir != "")
+}
+
+// CopyMedia copies the archived media of posts into the image store
+func (i TumblrArchiveImporter) CopyMedia(posts []tumblr.Post) error {
+	if i.ImageDir == "" {
+		return nil
+	}
+	return tumblr.CopyArchiveMedia(i.Path, i.ImageDir, posts)
+}
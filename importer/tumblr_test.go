@@ -0,0 +1,35 @@
+package importer
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/albertyw/reaction-pics/tumblr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTumblrArchiveImporter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "importer")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	csvPath := filepath.Join(dir, "posts.csv")
+	imageDir := filepath.Join(dir, "images")
+	assert.NoError(t, os.Mkdir(imageDir, 0755))
+	err = ioutil.WriteFile(csvPath, []byte("1234,Deploying on Friday,url,1234_0.gif,42\n"), 0644)
+	assert.NoError(t, err)
+	i := TumblrArchiveImporter{Path: "testdata/tumblr", ImageDir: imageDir}
+	assert.Equal(t, i.Name(), "tumblr")
+
+	buf := bytes.Buffer{}
+	change, err := Run(i, csvPath, false, &buf)
+	assert.NoError(t, err)
+	assert.Equal(t, len(change.Added), 1)
+	assert.Equal(t, change.Added[0].Image, tumblr.ImageURL("5678_0.gif"))
+	files, err := ioutil.ReadDir(imageDir)
+	assert.NoError(t, err)
+	assert.Equal(t, len(files), 1)
+	assert.Equal(t, files[0].Name(), "5678_0.gif")
+}
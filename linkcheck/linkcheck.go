@@ -0,0 +1,336 @@
+package linkcheck
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/albertyw/reaction-pics/tumblr"
+)
+
+const (
+	defaultConcurrency    = 8
+	defaultMaxAttempts    = 3
+	defaultBackoff        = time.Second
+	defaultHistorySize    = 10
+	defaultPermanentAfter = 3
+	requestTimeout        = 15 * time.Second
+)
+
+// LinkType describes which url of a post a link is
+const (
+	LinkTypePost  = "post"
+	LinkTypeImage = "image"
+)
+
+// Status is the result of checking a url once
+type Status struct {
+	Code      int       `json:"code"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// Dead returns whether the url could not be retrieved
+func (s Status) Dead() bool {
+	return s.Error != "" || s.Code >= 400
+}
+
+// Missing returns whether the url was reported as not existing
+func (s Status) Missing() bool {
+	return s.Code == http.StatusNotFound || s.Code == http.StatusGone
+}
+
+// retryable returns whether the check may succeed if tried again
+func (s Status) retryable() bool {
+	return s.Error != "" || s.Code == http.StatusTooManyRequests || s.Code >= 500
+}
+
+// DeadLink is a post url or image url whose latest check failed
+type DeadLink struct {
+	PostID    int64    `json:"postID"`
+	Title     string   `json:"title"`
+	Type      string   `json:"type"`
+	URL       string   `json:"url"`
+	Permanent bool     `json:"permanent"`
+	History   []Status `json:"history"`
+}
+
+// Checker checks post and image urls and remembers the results
+type Checker struct {
+	Client *http.Client
+	// Concurrency is the maximum number of simultaneous requests
+	Concurrency int
+	// MaxAttempts is the number of tries for a url before recording a failure
+	MaxAttempts int
+	// Backoff is the wait before the first retry, doubling for each retry
+	Backoff time.Duration
+	// HistorySize is the number of statuses remembered per url
+	HistorySize int
+	// PermanentAfter is the number of consecutive missing statuses before a
+	// url is considered permanently missing
+	PermanentAfter int
+	// HistoryPath is an optional file to persist the status history to
+	HistoryPath string
+
+	mut     sync.RWMutex
+	history map[string][]Status
+	hidden  []tumblr.Post
+}
+
+// NewChecker returns a Checker with default limits
+func NewChecker(client *http.Client) *Checker {
+	if client == nil {
+		client = &http.Client{Timeout: requestTimeout}
+	}
+	return &Checker{
+		Client:         client,
+		Concurrency:    defaultConcurrency,
+		MaxAttempts:    defaultMaxAttempts,
+		Backoff:        defaultBackoff,
+		HistorySize:    defaultHistorySize,
+		PermanentAfter: defaultPermanentAfter,
+		history:        map[string][]Status{},
+	}
+}
+
+// CheckURL requests a url, retrying with backoff on server and network
+// errors, and records the final status
+func (c *Checker) CheckURL(ctx context.Context, url string) Status {
+	var status Status
+	backoff := c.Backoff
+	for attempt := 0; attempt < c.MaxAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				status = Status{Error: ctx.Err().Error(), CheckedAt: time.Now()}
+				c.record(url, status)
+				return status
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+		status = c.request(ctx, url)
+		if !status.retryable() {
+			break
+		}
+	}
+	c.record(url, status)
+	return status
+}
+
+// request sends a HEAD request, falling back to GET for servers that do not
+// support HEAD
+func (c *Checker) request(ctx context.Context, url string) Status {
+	status := c.send(ctx, http.MethodHead, url)
+	if status.Code == http.StatusMethodNotAllowed {
+		status = c.send(ctx, http.MethodGet, url)
+	}
+	return status
+}
+
+func (c *Checker) send(ctx context.Context, method, url string) Status {
+	status := Status{CheckedAt: time.Now()}
+	request, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	response, err := c.Client.Do(request.WithContext(ctx))
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	response.Body.Close()
+	status.Code = response.StatusCode
+	return status
+}
+
+func (c *Checker) record(url string, status Status) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	history := append(c.history[url], status)
+	if len(history) > c.HistorySize {
+		history = history[len(history)-c.HistorySize:]
+	}
+	c.history[url] = history
+}
+
+// CheckPosts checks the url and image of every post.  Urls shared by several
+// posts are checked once.
+func (c *Checker) CheckPosts(ctx context.Context, posts []tumblr.Post) {
+	urls := make(chan string)
+	wg := sync.WaitGroup{}
+	for i := 0; i < c.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for url := range urls {
+				c.CheckURL(ctx, url)
+			}
+		}()
+	}
+	seen := map[string]bool{}
+	for _, post := range posts {
+		for _, url := range []string{post.URL, post.Image} {
+			if url == "" || seen[url] {
+				continue
+			}
+			seen[url] = true
+			select {
+			case urls <- url:
+			case <-ctx.Done():
+			}
+		}
+	}
+	close(urls)
+	wg.Wait()
+}
+
+// History returns the remembered statuses of a url, oldest first
+func (c *Checker) History(url string) []Status {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+	history := make([]Status, len(c.history[url]))
+	copy(history, c.history[url])
+	return history
+}
+
+// PermanentlyMissing returns whether the latest checks of a url were all
+// missing responses
+func (c *Checker) PermanentlyMissing(url string) bool {
+	history := c.History(url)
+	if len(history) < c.PermanentAfter {
+		return false
+	}
+	for _, status := range history[len(history)-c.PermanentAfter:] {
+		if !status.Missing() {
+			return false
+		}
+	}
+	return true
+}
+
+// Report returns the links of posts whose latest check failed
+func (c *Checker) Report(posts []tumblr.Post) []DeadLink {
+	deadLinks := []DeadLink{}
+	for _, post := range posts {
+		links := map[string]string{LinkTypePost: post.URL, LinkTypeImage: post.Image}
+		for _, linkType := range []string{LinkTypePost, LinkTypeImage} {
+			url := links[linkType]
+			history := c.History(url)
+			if len(history) == 0 || !history[len(history)-1].Dead() {
+				continue
+			}
+			deadLinks = append(deadLinks, DeadLink{
+				PostID:    post.ID,
+				Title:     post.Title,
+				Type:      linkType,
+				URL:       url,
+				Permanent: c.PermanentlyMissing(url),
+				History:   history,
+			})
+		}
+	}
+	sort.SliceStable(deadLinks, func(i, j int) bool {
+		return deadLinks[i].Permanent && !deadLinks[j].Permanent
+	})
+	return deadLinks
+}
+
+// HideMissingImages removes posts from the board whose image is permanently
+// missing and returns the number of removed posts
+func (c *Checker) HideMissingImages(board *tumblr.Board) int {
+	hidden := 0
+	for _, post := range board.FilterBoard("").Posts {
+		if c.PermanentlyMissing(post.Image) && board.RemovePost(post.ID) {
+			c.mut.Lock()
+			c.hidden = append(c.hidden, post)
+			c.mut.Unlock()
+			hidden++
+		}
+	}
+	return hidden
+}
+
+// Hidden returns the posts that have been removed by HideMissingImages
+func (c *Checker) Hidden() []tumblr.Post {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+	hidden := make([]tumblr.Post, len(c.hidden))
+	copy(hidden, c.hidden)
+	return hidden
+}
+
+// Run checks all posts of a board every interval until the context is done,
+// starting once saved posts have been read into the board.  If HistoryPath is
+// set, the status history is loaded before the first check and saved after
+// every check.
+func (c *Checker) Run(ctx context.Context, board *tumblr.Board, interval time.Duration, autoHide bool) error {
+	if c.HistoryPath != "" {
+		err := c.Load(c.HistoryPath)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	select {
+	case <-board.Loaded():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	for {
+		if autoHide {
+			c.HideMissingImages(board)
+		}
+		c.CheckPosts(ctx, board.FilterBoard("").Posts)
+		if autoHide {
+			c.HideMissingImages(board)
+		}
+		if c.HistoryPath != "" {
+			if err := c.Save(c.HistoryPath); err != nil {
+				return err
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Save writes the status history to a JSON file
+func (c *Checker) Save(path string) error {
+	c.mut.RLock()
+	data, err := json.Marshal(c.history)
+	c.mut.RUnlock()
+	if err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// Load reads the status history from a JSON file written by Save
+func (c *Checker) Load(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	history := map[string][]Status{}
+	if err := json.NewDecoder(file).Decode(&history); err != nil {
+		return err
+	}
+	c.mut.Lock()
+	c.history = history
+	c.mut.Unlock()
+	return nil
+}
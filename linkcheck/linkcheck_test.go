@@ -0,0 +1,162 @@
+package linkcheck
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/albertyw/reaction-pics/tumblr"
+	"github.com/stretchr/testify/assert"
+)
+
+func testServer() (*httptest.Server, *int32) {
+	flakyCount := int32(0)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("/flaky", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&flakyCount, 1) < 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+	mux.HandleFunc("/nohead", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	return httptest.NewServer(mux), &flakyCount
+}
+
+func testChecker() *Checker {
+	checker := NewChecker(nil)
+	checker.Backoff = time.Millisecond
+	return checker
+}
+
+func TestCheckURL(t *testing.T) {
+	server, _ := testServer()
+	defer server.Close()
+	checker := testChecker()
+
+	status := checker.CheckURL(context.Background(), server.URL+"/ok")
+	assert.Equal(t, status.Code, 200)
+	assert.False(t, status.Dead())
+
+	status = checker.CheckURL(context.Background(), server.URL+"/missing")
+	assert.Equal(t, status.Code, 404)
+	assert.True(t, status.Dead())
+	assert.True(t, status.Missing())
+
+	status = checker.CheckURL(context.Background(), server.URL+"/nohead")
+	assert.Equal(t, status.Code, 200)
+}
+
+func TestCheckURLRetries(t *testing.T) {
+	server, flakyCount := testServer()
+	defer server.Close()
+	checker := testChecker()
+
+	status := checker.CheckURL(context.Background(), server.URL+"/flaky")
+	assert.Equal(t, status.Code, 200)
+	assert.Equal(t, atomic.LoadInt32(flakyCount), int32(2))
+	assert.Equal(t, len(checker.History(server.URL+"/flaky")), 1)
+}
+
+func TestCheckURLUnreachable(t *testing.T) {
+	server, _ := testServer()
+	url := server.URL + "/ok"
+	server.Close()
+	checker := testChecker()
+
+	status := checker.CheckURL(context.Background(), url)
+	assert.NotEqual(t, status.Error, "")
+	assert.True(t, status.Dead())
+	assert.False(t, status.Missing())
+}
+
+func TestHistorySize(t *testing.T) {
+	checker := testChecker()
+	checker.HistorySize = 2
+	for i := 0; i < 3; i++ {
+		checker.record("url", Status{Code: 200 + i})
+	}
+	history := checker.History("url")
+	assert.Equal(t, len(history), 2)
+	assert.Equal(t, history[1].Code, 202)
+}
+
+func TestReportAndHide(t *testing.T) {
+	server, _ := testServer()
+	defer server.Close()
+	checker := testChecker()
+	checker.Concurrency = 2
+	checker.PermanentAfter = 2
+
+	board := tumblr.NewBoard([]tumblr.Post{})
+	board.AddPost(tumblr.Post{ID: 1, Title: "ok", URL: server.URL + "/ok", Image: server.URL + "/ok"})
+	board.AddPost(tumblr.Post{ID: 2, Title: "gone", URL: server.URL + "/ok", Image: server.URL + "/missing"})
+
+	checker.CheckPosts(context.Background(), board.Posts)
+	report := checker.Report(board.Posts)
+	assert.Equal(t, len(report), 1)
+	assert.Equal(t, report[0].PostID, int64(2))
+	assert.Equal(t, report[0].Type, LinkTypeImage)
+	assert.False(t, report[0].Permanent)
+	assert.Equal(t, checker.HideMissingImages(&board), 0)
+
+	checker.CheckPosts(context.Background(), board.Posts)
+	report = checker.Report(board.Posts)
+	assert.True(t, report[0].Permanent)
+	assert.Equal(t, checker.HideMissingImages(&board), 1)
+	assert.Equal(t, len(board.Posts), 1)
+	assert.Equal(t, checker.Hidden()[0].ID, int64(2))
+}
+
+func TestCheckPostsDuplicateURLs(t *testing.T) {
+	server, _ := testServer()
+	defer server.Close()
+	checker := testChecker()
+	checker.PermanentAfter = 2
+
+	posts := []tumblr.Post{
+		{ID: 1, URL: server.URL + "/ok", Image: server.URL + "/missing"},
+		{ID: 2, URL: server.URL + "/ok", Image: server.URL + "/missing"},
+		{ID: 3, URL: server.URL + "/missing", Image: server.URL + "/missing"},
+	}
+	checker.CheckPosts(context.Background(), posts)
+	assert.Equal(t, len(checker.History(server.URL+"/missing")), 1)
+	assert.Equal(t, len(checker.History(server.URL+"/ok")), 1)
+	assert.False(t, checker.PermanentlyMissing(server.URL+"/missing"))
+}
+
+func TestRunStopped(t *testing.T) {
+	checker := testChecker()
+	board := tumblr.NewBoard([]tumblr.Post{})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := checker.Run(ctx, &board, time.Hour, false)
+	assert.Equal(t, err, context.Canceled)
+}
+
+func TestSaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "linkcheck")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "history.json")
+
+	checker := testChecker()
+	checker.record("url", Status{Code: 404})
+	assert.NoError(t, checker.Save(path))
+
+	loaded := testChecker()
+	assert.NoError(t, loaded.Load(path))
+	assert.Equal(t, loaded.History("url")[0].Code, 404)
+}
@@ -5,7 +5,6 @@ import (
 	"path/filepath"
 	"runtime"
 
-	"github.com/albertyw/reaction-pics/server"
 	"github.com/joho/godotenv"
 	"github.com/newrelic/go-agent/v3/newrelic"
 	"github.com/rollbar/rollbar-go"
@@ -24,9 +23,10 @@ func (w logWriter) Write(p []byte) (int, error) {
 	return len(p), nil
 }
 
+// setupEnv loads environment variables from .env if it exists
 func setupEnv() {
 	err := godotenv.Load()
-	if err != nil {
+	if err != nil && !os.IsNotExist(err) {
 		panic(err)
 	}
 }
@@ -74,9 +74,5 @@ func getLogger() *zap.SugaredLogger {
 
 func main() {
 	setupEnv()
-	setupRollbar()
-	logger := getLogger()
-	defer logger.Sync()
-	newrelicApp := getNewRelicApp(logger)
-	server.Run(newrelicApp, logger)
+	os.Exit(runCommand(os.Args[1:], os.Stdout, os.Stderr))
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"io/ioutil"
 	"os"
 	"testing"
 
@@ -15,6 +16,18 @@ func TestSetupEnv(t *testing.T) {
 	assert.NotEqual(t, port, "")
 }
 
+func TestSetupEnvMissing(t *testing.T) {
+	origDir, err := os.Getwd()
+	assert.NoError(t, err)
+	defer os.Chdir(origDir)
+	dir, err := ioutil.TempDir("", "env")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, os.Chdir(dir))
+
+	assert.NotPanics(t, setupEnv)
+}
+
 func TestGetNewRelicApp(t *testing.T) {
 	setupEnv()
 	app := getNewRelicApp(zap.NewNop().Sugar())
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/albertyw/reaction-pics/tumblr"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultHost   = "https://www.reaction.pics"
+	searchLimit   = 20
+	remoteTimeout = 10 * time.Second
+)
+
+// searchResponse is the json returned by the /search endpoint
+type searchResponse struct {
+	Data         []tumblr.PostJSON `json:"data"`
+	Offset       int               `json:"offset"`
+	TotalResults int               `json:"totalResults"`
+}
+
+// searchLocal runs a search against the saved posts
+func searchLocal(query string, filter tumblr.ImageFilter, limit int) ([]tumblr.PostJSON, error) {
+	board := tumblr.LoadBoard()
+	err := board.ValidateImageFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	page, _ := board.Search(query, filter, 0, limit)
+	return *page.PostsToJSON(), nil
+}
+
+// searchRemote runs a search against the /search endpoint of a running server
+func searchRemote(host, query string, filter tumblr.ImageFilter) ([]tumblr.PostJSON, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	if filter.Type != "" {
+		params.Set("type", filter.Type)
+	}
+	if filter.MaxDuration > 0 {
+		params.Set("maxDuration", strconv.Itoa(filter.MaxDuration))
+	}
+	client := http.Client{Timeout: remoteTimeout}
+	response, err := client.Get(strings.TrimRight(host, "/") + "/search?" + params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusBadRequest {
+		message, _ := ioutil.ReadAll(io.LimitReader(response.Body, 1024))
+		return nil, errors.Errorf("Search failed: %s", strings.TrimSpace(string(message)))
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("Search failed with status %d", response.StatusCode)
+	}
+	data := searchResponse{}
+	err = json.NewDecoder(response.Body).Decode(&data)
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot parse search results")
+	}
+	return data.Data, nil
+}
+
+// pickPosts selects all posts, the top post, or a random post
+func pickPosts(posts []tumblr.PostJSON, pick string) ([]tumblr.PostJSON, error) {
+	if len(posts) == 0 {
+		return posts, nil
+	}
+	switch pick {
+	case "all":
+		return posts, nil
+	case "top":
+		return posts[:1], nil
+	case "random":
+		i := rand.New(rand.NewSource(time.Now().UnixNano())).Intn(len(posts))
+		return posts[i : i+1], nil
+	}
+	return nil, errors.Errorf("unknown pick: %s", pick)
+}
+
+// writePosts writes posts as a table, markdown image links, or json, with
+// links to the posts at host
+func writePosts(w io.Writer, posts []tumblr.PostJSON, format, host string) error {
+	host = strings.TrimRight(host, "/")
+	switch format {
+	case "table":
+		writer := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		for _, post := range posts {
+			fmt.Fprintf(writer, "%d\t%s\t%s\n", post.Likes, post.Title, host+post.InternalURL)
+		}
+		return writer.Flush()
+	case "markdown":
+		for _, post := range posts {
+			fmt.Fprintf(w, "[![%s](%s)](%s)\n", post.Title, post.Image, host+post.InternalURL)
+		}
+		return nil
+	case "json":
+		for i := range posts {
+			posts[i].InternalURL = host + posts[i].InternalURL
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(posts)
+	}
+	return errors.Errorf("unknown format: %s", format)
+}
+
+// searchCommand prints posts matching a query from the saved posts or a
+// running server
+func searchCommand(args []string, stdout, stderr io.Writer) int {
+	host := os.Getenv("HOST")
+	if host == "" {
+		host = defaultHost
+	}
+	flags := newFlagSet("search", "<query>", stderr)
+	remote := flags.String("remote", "", "url of a server to search instead of saved posts")
+	format := flags.String("format", "table", "output format: table, markdown, or json")
+	pick := flags.String("pick", "all", "results to print: all, top, or random")
+	limit := flags.Int("limit", searchLimit, "maximum number of results to search")
+	imageType := flags.String("type", "", "image type: static or animated")
+	maxDuration := flags.Int("max-duration", 0, "maximum animation duration in milliseconds")
+	linkHost := flags.String("host", "", "url that links to posts are built from (default remote or HOST)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *linkHost == "" {
+		*linkHost = host
+		if *remote != "" {
+			*linkHost = *remote
+		}
+	}
+	query := strings.Join(flags.Args(), " ")
+	filter := tumblr.ImageFilter{Type: *imageType, MaxDuration: *maxDuration}
+	if err := filter.Validate(); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	var posts []tumblr.PostJSON
+	var err error
+	if *remote != "" {
+		posts, err = searchRemote(*remote, query, filter)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		if len(posts) > *limit {
+			posts = posts[:*limit]
+		}
+	} else {
+		posts, err = searchLocal(query, filter, *limit)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	}
+	posts, err = pickPosts(posts, *pick)
+	if err == nil {
+		err = writePosts(stdout, posts, *format, *linkHost)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	if len(posts) == 0 {
+		fmt.Fprintln(stderr, "no results")
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/albertyw/reaction-pics/tumblr"
+	"github.com/stretchr/testify/assert"
+)
+
+func testSearchPosts() []tumblr.PostJSON {
+	posts := []tumblr.Post{
+		{ID: 1, Title: "title1", Image: "https://example.com/1.gif", Likes: 2},
+		{ID: 2, Title: "title2", Image: "https://example.com/2.gif", Likes: 1},
+	}
+	board := tumblr.NewBoard(posts)
+	return *board.PostsToJSON()
+}
+
+func TestSearchLocal(t *testing.T) {
+	posts, err := searchLocal("outage", tumblr.ImageFilter{}, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, len(posts), 3)
+	assert.Contains(t, posts[0].InternalURL, "/post/")
+	assert.True(t, posts[0].Likes >= posts[1].Likes)
+
+	_, err = searchLocal("outage", tumblr.ImageFilter{Type: tumblr.ImageTypeAnimated}, 3)
+	assert.Equal(t, err, tumblr.ErrNotIndexed)
+}
+
+func TestSearchRemote(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		data, _ := json.Marshal(searchResponse{Data: testSearchPosts(), TotalResults: 2})
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	filter := tumblr.ImageFilter{Type: tumblr.ImageTypeAnimated, MaxDuration: 100}
+	posts, err := searchRemote(server.URL+"/", "deploy", filter)
+	assert.NoError(t, err)
+	assert.Equal(t, query, "maxDuration=100&query=deploy&type=animated")
+	assert.Equal(t, len(posts), 2)
+	assert.Equal(t, posts[0].InternalURL, "/post/1/title1")
+}
+
+func TestSearchRemoteError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	_, err := searchRemote(server.URL, "deploy", tumblr.ImageFilter{})
+	assert.Error(t, err)
+}
+
+func TestSearchRemoteBadRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, tumblr.ErrNotIndexed.Error(), http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	_, err := searchRemote(server.URL, "deploy", tumblr.ImageFilter{Type: tumblr.ImageTypeStatic})
+	assert.EqualError(t, err, "Search failed: "+tumblr.ErrNotIndexed.Error())
+}
+
+func TestPickPosts(t *testing.T) {
+	posts := testSearchPosts()
+	picked, err := pickPosts(posts, "all")
+	assert.NoError(t, err)
+	assert.Equal(t, len(picked), 2)
+	picked, err = pickPosts(posts, "top")
+	assert.NoError(t, err)
+	assert.Equal(t, picked[0].ID, int64(1))
+	picked, err = pickPosts(posts, "random")
+	assert.NoError(t, err)
+	assert.Equal(t, len(picked), 1)
+	_, err = pickPosts(posts, "asdf")
+	assert.Error(t, err)
+}
+
+func TestWritePosts(t *testing.T) {
+	buf := bytes.Buffer{}
+	err := writePosts(&buf, testSearchPosts(), "table", "https://example.com/")
+	assert.NoError(t, err)
+	assert.Equal(t, buf.String(), "2  title1  https://example.com/post/1/title1\n1  title2  https://example.com/post/2/title2\n")
+
+	buf.Reset()
+	err = writePosts(&buf, testSearchPosts()[:1], "markdown", "https://example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, buf.String(), "[![title1](https://example.com/1.gif)](https://example.com/post/1/title1)\n")
+
+	buf.Reset()
+	err = writePosts(&buf, testSearchPosts(), "json", "https://example.com")
+	assert.NoError(t, err)
+	var posts []map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &posts))
+	assert.Equal(t, posts[1]["internalURL"], "https://example.com/post/2/title2")
+
+	err = writePosts(&buf, testSearchPosts(), "asdf", "")
+	assert.Error(t, err)
+}
+
+func TestSearchCommand(t *testing.T) {
+	stdout := bytes.Buffer{}
+	args := []string{"-pick", "top", "-format", "markdown", "-host", "https://example.com", "outage"}
+	code := searchCommand(args, &stdout, &bytes.Buffer{})
+	assert.Equal(t, code, 0)
+	assert.Contains(t, stdout.String(), "(https://example.com/post/")
+	assert.Equal(t, bytes.Count(stdout.Bytes(), []byte("\n")), 1)
+
+	code = searchCommand([]string{"asdfasdfasdf"}, &bytes.Buffer{}, &bytes.Buffer{})
+	assert.Equal(t, code, 1)
+}
+
+func TestSearchCommandInvalidFilter(t *testing.T) {
+	stderr := bytes.Buffer{}
+	code := searchCommand([]string{"-type", "video", "outage"}, &bytes.Buffer{}, &stderr)
+	assert.Equal(t, code, 2)
+	assert.Contains(t, stderr.String(), "Unknown image type video")
+
+	stderr.Reset()
+	code = searchCommand([]string{"-max-duration", "-1", "outage"}, &bytes.Buffer{}, &stderr)
+	assert.Equal(t, code, 2)
+	assert.Contains(t, stderr.String(), "Invalid max duration -1")
+}
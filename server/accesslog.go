@@ -0,0 +1,121 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/rollbar/rollbar-go"
+	"go.uber.org/zap"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// validRequestID matches request ids from clients and proxies that are safe
+// to log and echo back
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// requestIDKey is the context key of the request id
+type requestIDKey struct{}
+
+// newRequestID returns a random request id
+func newRequestID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// withRequestID returns a request with the id from its X-Request-ID header, or
+// a new id if the header is missing or invalid, in its context
+func withRequestID(r *http.Request) (*http.Request, string) {
+	id := r.Header.Get(requestIDHeader)
+	if !validRequestID.MatchString(id) {
+		id = newRequestID()
+	}
+	return r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)), id
+}
+
+// requestID returns the id of a request
+func requestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDKey{}).(string)
+	return id
+}
+
+// rollbarRequestError reports a request error to Rollbar with the request id
+func rollbarRequestError(level string, r *http.Request, err error) {
+	extras := map[string]interface{}{"requestID": requestID(r)}
+	rollbar.RequestErrorWithExtras(level, r, err, extras)
+}
+
+// accessLogWriter records the status code and size of a response
+type accessLogWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+// WriteHeader records the status code of the response
+func (w *accessLogWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the number of bytes in the response body
+func (w *accessLogWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(data)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Flush sends buffered data to the client, which streaming rpcs rely on
+func (w *accessLogWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying response writer for http.ResponseController
+func (w *accessLogWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// accessLog wraps a handler to assign a request id, and to log the request
+// and record its metrics after the handler returns
+func (g handlerGenerator) accessLog(pattern string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		r, id := withRequestID(r)
+		w.Header().Set(requestIDHeader, id)
+		writer := &accessLogWriter{ResponseWriter: w}
+		handler(writer, r)
+		duration := time.Since(start)
+		if writer.status == 0 {
+			writer.status = http.StatusOK
+		}
+		g.deps.metrics.observeRequest(pattern, r.Method, writer.status, duration)
+		g.logger.Desugar().Info("request",
+			zap.String("requestID", id),
+			zap.String("method", r.Method),
+			zap.String("url", r.URL.String()),
+			zap.String("route", pattern),
+			zap.Int("status", writer.status),
+			zap.Int64("bytes", writer.bytes),
+			zap.Duration("duration", duration),
+			zap.String("clientIP", g.limiter.clientIP(r)),
+			zap.String("userAgent", r.UserAgent()),
+			zap.String("referer", r.Referer()),
+			zap.String("proto", r.Proto),
+		)
+	}
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/albertyw/reaction-pics/tumblr"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func accessLogTestGenerator() (handlerGenerator, *observer.ObservedLogs) {
+	core, logs := observer.New(zap.DebugLevel)
+	logger := zap.New(core).Sugar()
+	board := tumblr.NewBoard([]tumblr.Post{})
+	generator := handlerGenerator{
+		logger: logger,
+		deps:   handlerDeps{logger: logger, board: &board},
+	}
+	return generator, logs
+}
+
+func TestWithRequestID(t *testing.T) {
+	request := httptest.NewRequest("GET", "/", nil)
+	request.Header.Set(requestIDHeader, "abc-123")
+	request, id := withRequestID(request)
+	assert.Equal(t, "abc-123", id)
+	assert.Equal(t, "abc-123", requestID(request))
+
+	request = httptest.NewRequest("GET", "/", nil)
+	request.Header.Set(requestIDHeader, "bad id\n")
+	request, id = withRequestID(request)
+	assert.Len(t, id, 32)
+	assert.Equal(t, id, requestID(request))
+
+	assert.Equal(t, "", requestID(httptest.NewRequest("GET", "/", nil)))
+	assert.NotEqual(t, newRequestID(), newRequestID())
+}
+
+func TestAccessLog(t *testing.T) {
+	generator, logs := accessLogTestGenerator()
+	_, handler := generator.newHandler("/post/", func(w http.ResponseWriter, r *http.Request, d handlerDeps) {
+		d.logger.Warn("handler log")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+	})
+	request := httptest.NewRequest("GET", "/post/1?a=b", nil)
+	request.RemoteAddr = "1.2.3.4:5678"
+	request.Header.Set("User-Agent", "test-agent")
+	request.Header.Set(requestIDHeader, "request-1")
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	assert.Equal(t, "request-1", response.Header().Get(requestIDHeader))
+
+	entries := logs.All()
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "handler log", entries[0].Message)
+	assert.Equal(t, "request-1", entries[0].ContextMap()["requestID"])
+
+	assert.Equal(t, "request", entries[1].Message)
+	fields := entries[1].ContextMap()
+	assert.Equal(t, "request-1", fields["requestID"])
+	assert.Equal(t, "GET", fields["method"])
+	assert.Equal(t, "/post/1?a=b", fields["url"])
+	assert.Equal(t, "/post/", fields["route"])
+	assert.Equal(t, int64(404), fields["status"])
+	assert.Equal(t, int64(9), fields["bytes"])
+	assert.Equal(t, "1.2.3.4", fields["clientIP"])
+	assert.Equal(t, "test-agent", fields["userAgent"])
+	assert.Contains(t, fields, "duration")
+}
+
+func TestAccessLogDefaultStatus(t *testing.T) {
+	generator, logs := accessLogTestGenerator()
+	_, handler := generator.newHandler("/", func(w http.ResponseWriter, r *http.Request, d handlerDeps) {
+		http.NewResponseController(w).Flush()
+	})
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, httptest.NewRequest("GET", "/", nil))
+	assert.True(t, response.Flushed)
+	fields := logs.All()[0].ContextMap()
+	assert.Equal(t, int64(200), fields["status"])
+	assert.Equal(t, int64(0), fields["bytes"])
+	assert.Len(t, fields["requestID"], 32)
+}
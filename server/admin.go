@@ -0,0 +1,54 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/albertyw/reaction-pics/linkcheck"
+	"github.com/albertyw/reaction-pics/tumblr"
+	"github.com/pkg/errors"
+	"github.com/rollbar/rollbar-go"
+)
+
+// adminAuthorized returns whether the request has a bearer token matching
+// ADMIN_TOKEN.  Admin access is disabled if ADMIN_TOKEN is not set.
+func adminAuthorized(r *http.Request) bool {
+	token := os.Getenv("ADMIN_TOKEN")
+	if token == "" {
+		return false
+	}
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return false
+	}
+	given := strings.TrimPrefix(auth, "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+// adminLinksHandler returns a json report of dead post and image links
+func adminLinksHandler(w http.ResponseWriter, r *http.Request, d handlerDeps) {
+	if !adminAuthorized(r) {
+		err := errors.New("Unauthorized admin request")
+		d.logger.Warn(err)
+		rollbarRequestError(rollbar.WARN, r, err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if d.linkChecker == nil {
+		http.Error(w, "Link checking is not enabled", http.StatusNotFound)
+		return
+	}
+	data := struct {
+		DeadLinks []linkcheck.DeadLink `json:"deadLinks"`
+		Hidden    []tumblr.Post        `json:"hidden"`
+	}{
+		DeadLinks: d.linkChecker.Report(d.board.FilterBoard("").Posts),
+		Hidden:    d.linkChecker.Hidden(),
+	}
+	report, _ := json.Marshal(data)
+	fmt.Fprint(w, string(report))
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/albertyw/reaction-pics/linkcheck"
+	"github.com/albertyw/reaction-pics/tumblr"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestAdminAuthorized(t *testing.T) {
+	origToken := os.Getenv("ADMIN_TOKEN")
+	defer os.Setenv("ADMIN_TOKEN", origToken)
+
+	request, err := http.NewRequest("GET", "/admin/links", nil)
+	assert.NoError(t, err)
+	os.Setenv("ADMIN_TOKEN", "")
+	assert.False(t, adminAuthorized(request))
+
+	os.Setenv("ADMIN_TOKEN", "secret")
+	assert.False(t, adminAuthorized(request))
+	request.Header.Set("Authorization", "Bearer wrong")
+	assert.False(t, adminAuthorized(request))
+	request.Header.Set("Authorization", "Bearer secret")
+	assert.True(t, adminAuthorized(request))
+}
+
+func TestAdminLinksHandler(t *testing.T) {
+	origToken := os.Getenv("ADMIN_TOKEN")
+	defer os.Setenv("ADMIN_TOKEN", origToken)
+	os.Setenv("ADMIN_TOKEN", "secret")
+
+	board := tumblr.NewBoard([]tumblr.Post{})
+	deps := handlerDeps{logger: zap.NewNop().Sugar(), board: &board}
+	request, err := http.NewRequest("GET", "/admin/links", nil)
+	assert.NoError(t, err)
+	response := httptest.NewRecorder()
+	adminLinksHandler(response, request, deps)
+	assert.Equal(t, response.Code, 401)
+
+	request.Header.Set("Authorization", "Bearer secret")
+	response = httptest.NewRecorder()
+	adminLinksHandler(response, request, deps)
+	assert.Equal(t, response.Code, 404)
+
+	deps.linkChecker = linkcheck.NewChecker(nil)
+	response = httptest.NewRecorder()
+	adminLinksHandler(response, request, deps)
+	assert.Equal(t, response.Code, 200)
+	assert.Equal(t, response.Body.String(), "{\"deadLinks\":[],\"hidden\":[]}")
+}
@@ -0,0 +1,252 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/albertyw/reaction-pics/tumblr"
+	"github.com/pkg/errors"
+	"github.com/rollbar/rollbar-go"
+)
+
+const apiPrefix = "/api/v1"
+
+// apiPost is a post in api responses
+type apiPost struct {
+	ID        int64             `json:"id"`
+	Title     string            `json:"title"`
+	URL       string            `json:"url"`
+	Permalink string            `json:"permalink"`
+	Image     string            `json:"image"`
+	Likes     int64             `json:"likes"`
+	Tags      []string          `json:"tags,omitempty"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Meta      *tumblr.ImageMeta `json:"meta,omitempty"`
+}
+
+// apiSearchResponse is a page of posts matching a search
+type apiSearchResponse struct {
+	Posts        []apiPost `json:"posts"`
+	Offset       int       `json:"offset"`
+	TotalResults int       `json:"totalResults"`
+	Next         string    `json:"next,omitempty"`
+}
+
+// apiPostResponse is a single post
+type apiPostResponse struct {
+	Post apiPost `json:"post"`
+}
+
+// apiCacheStats is the number of hits, misses, and entries of a cache
+type apiCacheStats struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+	Size   int   `json:"size"`
+}
+
+// apiStatsResponse is statistics about the board
+type apiStatsResponse struct {
+	PostCount   int           `json:"postCount"`
+	Keywords    []string      `json:"keywords"`
+	SearchCache apiCacheStats `json:"searchCache"`
+}
+
+// apiTimeResponse is the current server time
+type apiTimeResponse struct {
+	UnixTime int64 `json:"unixtime"`
+}
+
+// apiErrorBody describes an api error
+type apiErrorBody struct {
+	Status  int    `json:"status"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// apiErrorResponse is the envelope of every api error
+type apiErrorResponse struct {
+	Error apiErrorBody `json:"error"`
+}
+
+// newAPIPost converts a post to an api post with absolute urls
+func newAPIPost(post tumblr.Post) apiPost {
+	return apiPost{
+		ID:        post.ID,
+		Title:     post.Title,
+		URL:       post.URL,
+		Permalink: os.Getenv("HOST") + post.InternalURL(),
+		Image:     post.Image,
+		Likes:     post.Likes,
+		Tags:      post.Tags,
+		Timestamp: post.Timestamp,
+		Meta:      post.Meta,
+	}
+}
+
+// findPost returns the post for an id from a url path
+func findPost(board *tumblr.Board, postIDString string) (*tumblr.Post, error) {
+	postID, err := strconv.ParseInt(postIDString, 10, 64)
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot parse post id")
+	}
+	post := board.GetPostByID(postID)
+	if post == nil {
+		return nil, errors.New("Cannot find post")
+	}
+	return post, nil
+}
+
+// getStats returns statistics about the board and search cache
+func getStats(d handlerDeps) apiStatsResponse {
+	return apiStatsResponse{
+		PostCount:   d.board.Len(),
+		Keywords:    d.board.Keywords(),
+		SearchCache: d.searches.stats(),
+	}
+}
+
+// getTime returns the current server time
+func getTime() apiTimeResponse {
+	return apiTimeResponse{UnixTime: time.Now().Unix()}
+}
+
+// writeAPIJSON writes an api response as json
+func writeAPIJSON(w http.ResponseWriter, r *http.Request, d handlerDeps, status int, response interface{}) {
+	data, err := json.Marshal(response)
+	if err != nil {
+		err = errors.Wrap(err, "Cannot marshal api response")
+		d.logger.Error(err)
+		rollbarRequestError(rollbar.ERR, r, err)
+		status = http.StatusInternalServerError
+		data, _ = json.Marshal(apiErrorResponse{apiErrorBody{status, "internal", "Cannot marshal response"}})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+// writeAPIError writes an api error in the error envelope
+func writeAPIError(w http.ResponseWriter, r *http.Request, d handlerDeps, status int, code, message string) {
+	response := apiErrorResponse{apiErrorBody{Status: status, Code: code, Message: message}}
+	writeAPIJSON(w, r, d, status, response)
+}
+
+// apiSearchHandler returns a page of posts matching a query
+func apiSearchHandler(w http.ResponseWriter, r *http.Request, d handlerDeps) {
+	page, err := searchResultsFromRequest(r, d)
+	if err != nil {
+		writeAPIError(w, r, d, http.StatusBadRequest, "invalid_argument", err.Error())
+		return
+	}
+	response := apiSearchResponse{
+		Posts:        []apiPost{},
+		Offset:       page.Offset,
+		TotalResults: page.TotalResults,
+	}
+	for _, post := range page.Data {
+		response.Posts = append(response.Posts, newAPIPost(post.Post))
+	}
+	if page.HasNext() {
+		params := r.URL.Query()
+		params.Set("offset", strconv.Itoa(page.NextOffset()))
+		response.Next = os.Getenv("HOST") + apiPrefix + "/search?" + params.Encode()
+	}
+	writeAPIJSON(w, r, d, http.StatusOK, response)
+}
+
+// apiPostHandler returns a post by id
+func apiPostHandler(w http.ResponseWriter, r *http.Request, d handlerDeps) {
+	postIDString := strings.TrimPrefix(r.URL.Path, apiPrefix+"/posts/")
+	post, err := findPost(d.board, postIDString)
+	if err != nil {
+		writeAPIError(w, r, d, http.StatusNotFound, "not_found", err.Error())
+		return
+	}
+	writeAPIJSON(w, r, d, http.StatusOK, apiPostResponse{Post: newAPIPost(*post)})
+}
+
+// apiStatsHandler returns statistics about the board
+func apiStatsHandler(w http.ResponseWriter, r *http.Request, d handlerDeps) {
+	writeAPIJSON(w, r, d, http.StatusOK, getStats(d))
+}
+
+// apiTimeHandler returns the current server time
+func apiTimeHandler(w http.ResponseWriter, r *http.Request, d handlerDeps) {
+	writeAPIJSON(w, r, d, http.StatusOK, getTime())
+}
+
+// apiNotFoundHandler returns an error for unknown api paths
+func apiNotFoundHandler(w http.ResponseWriter, r *http.Request, d handlerDeps) {
+	writeAPIError(w, r, d, http.StatusNotFound, "not_found", "Unknown api path: "+r.URL.Path)
+}
+
+// apiParameter is a query or path parameter of an api route
+type apiParameter struct {
+	Name        string
+	In          string
+	Type        string
+	Description string
+}
+
+// apiRoute is an api endpoint, which is used both for routing and for
+// generating the OpenAPI document
+type apiRoute struct {
+	pattern     string
+	path        string
+	operationID string
+	summary     string
+	parameters  []apiParameter
+	response    interface{}
+	handler     handlerWithDeps
+}
+
+// apiRoutes returns the endpoints of the api
+func apiRoutes() []apiRoute {
+	return []apiRoute{
+		{
+			pattern:     apiPrefix + "/search",
+			path:        apiPrefix + "/search",
+			operationID: "search",
+			summary:     "Search posts by title, ranked by likes",
+			parameters: []apiParameter{
+				{"query", "query", "string", "Keywords to match against post titles; empty returns random posts"},
+				{"offset", "query", "integer", "Number of results to skip"},
+				{"type", "query", "string", "Image type: static or animated"},
+				{"maxDuration", "query", "integer", "Maximum animation duration in milliseconds"},
+			},
+			response: apiSearchResponse{},
+			handler:  apiSearchHandler,
+		},
+		{
+			pattern:     apiPrefix + "/posts/",
+			path:        apiPrefix + "/posts/{id}",
+			operationID: "getPost",
+			summary:     "Get a post by id",
+			parameters: []apiParameter{
+				{"id", "path", "integer", "Post id"},
+			},
+			response: apiPostResponse{},
+			handler:  apiPostHandler,
+		},
+		{
+			pattern:     apiPrefix + "/stats",
+			path:        apiPrefix + "/stats",
+			operationID: "getStats",
+			summary:     "Get statistics about indexed posts",
+			response:    apiStatsResponse{},
+			handler:     apiStatsHandler,
+		},
+		{
+			pattern:     apiPrefix + "/time",
+			path:        apiPrefix + "/time",
+			operationID: "getTime",
+			summary:     "Get the current server time",
+			response:    apiTimeResponse{},
+			handler:     apiTimeHandler,
+		},
+	}
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+
+	"github.com/albertyw/reaction-pics/tumblr"
+	"github.com/stretchr/testify/assert"
+)
+
+func apiTestPosts() []tumblr.Post {
+	posts := []tumblr.Post{}
+	for i := 1; i <= maxResults+1; i++ {
+		posts = append(posts, tumblr.Post{
+			ID:    int64(i),
+			Title: "Outage " + string(rune('a'+i)),
+			URL:   "https://reactionpics.tumblr.com/post/1",
+			Image: "https://example.com/1.gif",
+			Likes: int64(i),
+		})
+	}
+	return posts
+}
+
+func (s *HandlerTestSuite) apiRequest(handler handlerWithDeps, path string) *httptest.ResponseRecorder {
+	request, err := http.NewRequest("GET", path, nil)
+	assert.NoError(s.T(), err)
+	response := httptest.NewRecorder()
+	handler(response, request, s.deps)
+	assert.Equal(s.T(), response.Header().Get("Content-Type"), "application/json")
+	return response
+}
+
+func (s *HandlerTestSuite) TestAPISearchHandler() {
+	s.addPosts(apiTestPosts())
+	response := s.apiRequest(apiSearchHandler, "/api/v1/search?query=outage")
+	assert.Equal(s.T(), response.Code, 200)
+	data := apiSearchResponse{}
+	assert.NoError(s.T(), json.Unmarshal(response.Body.Bytes(), &data))
+	assert.Equal(s.T(), len(data.Posts), maxResults)
+	assert.Equal(s.T(), data.TotalResults, maxResults+1)
+	post := data.Posts[0]
+	assert.Equal(s.T(), post.Permalink, os.Getenv("HOST")+tumblr.Post{ID: post.ID, Title: post.Title}.InternalURL())
+	next, err := url.Parse(data.Next)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), next.Path, "/api/v1/search")
+	assert.Equal(s.T(), next.Query().Get("offset"), "20")
+	assert.Equal(s.T(), next.Query().Get("query"), "outage")
+
+	response = s.apiRequest(apiSearchHandler, "/api/v1/search?query=outage&offset=20")
+	data = apiSearchResponse{}
+	assert.NoError(s.T(), json.Unmarshal(response.Body.Bytes(), &data))
+	assert.Equal(s.T(), len(data.Posts), 1)
+	assert.Equal(s.T(), data.Next, "")
+}
+
+func (s *HandlerTestSuite) TestAPISearchHandlerEmpty() {
+	s.addPosts(apiTestPosts())
+	response := s.apiRequest(apiSearchHandler, "/api/v1/search?query=asdf")
+	assert.Equal(s.T(), response.Body.String(), `{"posts":[],"offset":0,"totalResults":0}`)
+}
+
+func (s *HandlerTestSuite) TestAPISearchHandlerInvalidImageFilter() {
+	s.addPosts(apiTestPosts())
+	response := s.apiRequest(apiSearchHandler, "/api/v1/search?query=outage&maxDuration=-1")
+	assert.Equal(s.T(), response.Code, 400)
+	data := apiErrorResponse{}
+	assert.NoError(s.T(), json.Unmarshal(response.Body.Bytes(), &data))
+	assert.Equal(s.T(), data.Error.Code, "invalid_argument")
+	assert.Equal(s.T(), data.Error.Message, "Invalid max duration -1")
+}
+
+func (s *HandlerTestSuite) TestAPIPostHandler() {
+	s.addPosts(apiTestPosts())
+	response := s.apiRequest(apiPostHandler, "/api/v1/posts/2")
+	assert.Equal(s.T(), response.Code, 200)
+	data := apiPostResponse{}
+	assert.NoError(s.T(), json.Unmarshal(response.Body.Bytes(), &data))
+	assert.Equal(s.T(), data.Post.ID, int64(2))
+	assert.Equal(s.T(), data.Post.URL, "https://reactionpics.tumblr.com/post/1")
+}
+
+func (s *HandlerTestSuite) TestAPIPostHandlerNotFound() {
+	s.addPosts(apiTestPosts())
+	for _, path := range []string{"/api/v1/posts/1234", "/api/v1/posts/asdf", "/api/v1/posts/"} {
+		response := s.apiRequest(apiPostHandler, path)
+		assert.Equal(s.T(), response.Code, 404)
+		data := apiErrorResponse{}
+		assert.NoError(s.T(), json.Unmarshal(response.Body.Bytes(), &data))
+		assert.Equal(s.T(), data.Error.Status, 404)
+		assert.Equal(s.T(), data.Error.Code, "not_found")
+	}
+}
+
+func (s *HandlerTestSuite) TestAPIStatsHandler() {
+	s.addPosts(apiTestPosts())
+	response := s.apiRequest(apiStatsHandler, "/api/v1/stats")
+	data := apiStatsResponse{}
+	assert.NoError(s.T(), json.Unmarshal(response.Body.Bytes(), &data))
+	assert.Equal(s.T(), data.PostCount, maxResults+1)
+}
+
+func (s *HandlerTestSuite) TestAPITimeHandler() {
+	response := s.apiRequest(apiTimeHandler, "/api/v1/time")
+	data := apiTimeResponse{}
+	assert.NoError(s.T(), json.Unmarshal(response.Body.Bytes(), &data))
+	assert.True(s.T(), data.UnixTime > 0)
+}
+
+func (s *HandlerTestSuite) TestAPINotFoundHandler() {
+	response := s.apiRequest(apiNotFoundHandler, "/api/v1/asdf")
+	assert.Equal(s.T(), response.Code, 404)
+	assert.Contains(s.T(), response.Body.String(), `"code":"not_found"`)
+}
+
+func (s *HandlerTestSuite) TestWriteAPIJSONError() {
+	request, err := http.NewRequest("GET", "/api/v1/asdf", nil)
+	assert.NoError(s.T(), err)
+	response := httptest.NewRecorder()
+	writeAPIJSON(response, request, s.deps, 200, map[string]interface{}{"a": func() {}})
+	assert.Equal(s.T(), response.Code, 500)
+	assert.Contains(s.T(), response.Body.String(), `"code":"internal"`)
+}
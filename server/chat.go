@@ -0,0 +1,112 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"os"
+
+	"github.com/albertyw/reaction-pics/tumblr"
+	"github.com/pkg/errors"
+	"github.com/rollbar/rollbar-go"
+)
+
+// chatReply is the reaction found for a query from a chat platform
+type chatReply struct {
+	Query string
+	Post  *tumblr.Post
+}
+
+// newChatReply finds the top post matching a query, like the first search
+// result on the website
+func newChatReply(board *tumblr.Board, query string) chatReply {
+	reply := chatReply{Query: query}
+	page, _ := board.Search(query, tumblr.ImageFilter{}, 0, maxResults)
+	if len(page.Posts) > 0 {
+		reply.Post = &page.Posts[0]
+	}
+	return reply
+}
+
+// shuffle returns a reply with a random post matching the query other than
+// the current post
+func (c chatReply) shuffle(board *tumblr.Board) chatReply {
+	_, total := board.Search(c.Query, tumblr.ImageFilter{}, 0, 0)
+	page, _ := board.Search(c.Query, tumblr.ImageFilter{}, 0, total)
+	candidates := []tumblr.Post{}
+	for _, post := range page.Posts {
+		if c.Post == nil || post.ID != c.Post.ID {
+			candidates = append(candidates, post)
+		}
+	}
+	if len(candidates) == 0 {
+		return c
+	}
+	return chatReply{Query: c.Query, Post: &candidates[rand.Intn(len(candidates))]}
+}
+
+// Found returns whether a post matched the query
+func (c chatReply) Found() bool {
+	return c.Post != nil
+}
+
+// Title returns the title of the post
+func (c chatReply) Title() string {
+	return c.Post.Title
+}
+
+// ImageURL returns the url of the post image
+func (c chatReply) ImageURL() string {
+	return c.Post.Image
+}
+
+// PostURL returns the absolute url of the post page
+func (c chatReply) PostURL() string {
+	return os.Getenv("HOST") + c.Post.InternalURL()
+}
+
+// NotFoundText returns a message for when no post matched the query
+func (c chatReply) NotFoundText() string {
+	return fmt.Sprintf("No reactions found for \"%s\"", c.Query)
+}
+
+// chatPlatform is a chat service that sends queries as webhook requests and
+// shows the reply from the response body
+type chatPlatform interface {
+	// verify checks that a request body was sent by the platform
+	verify(r *http.Request, body []byte) error
+	// query extracts the search query from a request body
+	query(body []byte) (string, error)
+	// render converts a reply into the json response for the platform
+	render(reply chatReply) interface{}
+}
+
+// chatHandler returns a handler that answers queries from a chat platform
+func chatHandler(platform chatPlatform) handlerWithDeps {
+	return func(w http.ResponseWriter, r *http.Request, d handlerDeps) {
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+		if err == nil {
+			err = platform.verify(r, body)
+		}
+		if err != nil {
+			d.logger.Warn(err)
+			rollbarRequestError(rollbar.WARN, r, err)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		query, err := platform.query(body)
+		if err != nil {
+			err = errors.Wrap(err, "Cannot parse chat request")
+			d.logger.Warn(err)
+			rollbarRequestError(rollbar.WARN, r, err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		reply := newChatReply(d.board, query)
+		data, _ := json.Marshal(platform.render(reply))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	}
+}
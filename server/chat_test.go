@@ -0,0 +1,92 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+
+	"github.com/albertyw/reaction-pics/tumblr"
+	"github.com/stretchr/testify/assert"
+)
+
+var chatTestPosts = []tumblr.Post{
+	{ID: 1, Title: "Outage one", Image: "https://example.com/1.gif", Likes: 2},
+	{ID: 2, Title: "Outage two", Image: "https://example.com/2.gif", Likes: 1},
+	{ID: 3, Title: "Deploy", Image: "https://example.com/3.gif", Likes: 3},
+}
+
+func (s *HandlerTestSuite) TestNewChatReply() {
+	s.addPosts(chatTestPosts)
+	reply := newChatReply(s.deps.board, "outage")
+	assert.True(s.T(), reply.Found())
+	assert.Equal(s.T(), reply.Title(), "Outage one")
+	assert.Equal(s.T(), reply.ImageURL(), "https://example.com/1.gif")
+	assert.Equal(s.T(), reply.PostURL(), os.Getenv("HOST")+"/post/1/outage-one")
+
+	reply = newChatReply(s.deps.board, "asdf")
+	assert.False(s.T(), reply.Found())
+	assert.Equal(s.T(), reply.NotFoundText(), `No reactions found for "asdf"`)
+}
+
+func (s *HandlerTestSuite) TestChatReplyShuffle() {
+	s.addPosts(chatTestPosts)
+	reply := newChatReply(s.deps.board, "outage").shuffle(s.deps.board)
+	assert.Equal(s.T(), reply.Post.ID, int64(2))
+	assert.Equal(s.T(), reply.Query, "outage")
+
+	reply = newChatReply(s.deps.board, "deploy").shuffle(s.deps.board)
+	assert.Equal(s.T(), reply.Post.ID, int64(3))
+
+	reply = newChatReply(s.deps.board, "asdf").shuffle(s.deps.board)
+	assert.False(s.T(), reply.Found())
+}
+
+type testChatPlatform struct {
+	verifyErr error
+}
+
+func (p testChatPlatform) verify(r *http.Request, body []byte) error {
+	return p.verifyErr
+}
+
+func (p testChatPlatform) query(body []byte) (string, error) {
+	return string(body), nil
+}
+
+func (p testChatPlatform) render(reply chatReply) interface{} {
+	if !reply.Found() {
+		return reply.NotFoundText()
+	}
+	return reply.Title()
+}
+
+func (s *HandlerTestSuite) TestChatHandler() {
+	s.addPosts(chatTestPosts)
+	request, err := http.NewRequest("POST", "/integrations/test", strings.NewReader("deploy"))
+	assert.NoError(s.T(), err)
+	response := httptest.NewRecorder()
+	chatHandler(testChatPlatform{})(response, request, s.deps)
+	assert.Equal(s.T(), response.Code, 200)
+	assert.Equal(s.T(), response.Header().Get("Content-Type"), "application/json")
+	assert.Equal(s.T(), response.Body.String(), `"Deploy"`)
+}
+
+func (s *HandlerTestSuite) TestChatHandlerUnauthorized() {
+	s.addPosts(chatTestPosts)
+	request, err := http.NewRequest("POST", "/integrations/test", strings.NewReader("deploy"))
+	assert.NoError(s.T(), err)
+	response := httptest.NewRecorder()
+	platform := testChatPlatform{verifyErr: os.ErrPermission}
+	chatHandler(platform)(response, request, s.deps)
+	assert.Equal(s.T(), response.Code, 401)
+}
+
+func (s *HandlerTestSuite) TestChatHandlerTooLarge() {
+	s.addPosts(chatTestPosts)
+	request, err := http.NewRequest("POST", "/integrations/test", strings.NewReader(strings.Repeat("a", maxRequestBodySize+1)))
+	assert.NoError(s.T(), err)
+	response := httptest.NewRecorder()
+	chatHandler(testChatPlatform{})(response, request, s.deps)
+	assert.Equal(s.T(), response.Code, 401)
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/albertyw/reaction-pics/tumblr"
+)
+
+// boardCacheControl lets browsers and CDNs store responses derived from the
+// board, but revalidate them so that they change as soon as the board does
+const boardCacheControl = "public, max-age=0, must-revalidate"
+
+// boardETag returns an entity tag that changes whenever the posts of the
+// board change. The update time distinguishes boards in different processes
+// that happen to have the same version.
+func boardETag(board *tumblr.Board) string {
+	return fmt.Sprintf(`W/"%d-%x"`, board.Version(), board.Updated().UnixNano())
+}
+
+// etagMatches returns whether an If-None-Match header matches an entity tag
+// using weak comparison
+func etagMatches(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkBoardCache sets caching headers derived from the board version, and
+// writes a 304 response and returns true if the client already has the
+// current response
+func checkBoardCache(w http.ResponseWriter, r *http.Request, board *tumblr.Board) bool {
+	etag := boardETag(board)
+	updated := board.Updated().UTC().Truncate(time.Second)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", updated.Format(http.TimeFormat))
+	w.Header().Set("Cache-Control", boardCacheControl)
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	notModified := false
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		notModified = etagMatches(header, etag)
+	} else if header := r.Header.Get("If-Modified-Since"); header != "" {
+		since, err := http.ParseTime(header)
+		notModified = err == nil && !updated.After(since)
+	}
+	if notModified {
+		w.WriteHeader(http.StatusNotModified)
+	}
+	return notModified
+}
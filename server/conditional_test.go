@@ -0,0 +1,116 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/albertyw/reaction-pics/tumblr"
+	"github.com/stretchr/testify/assert"
+)
+
+func (s *HandlerTestSuite) TestBoardETag() {
+	s.addPosts(chatTestPosts)
+	etag := boardETag(s.deps.board)
+	assert.Equal(s.T(), etag, boardETag(s.deps.board))
+	assert.Regexp(s.T(), `^W/"3-[0-9a-f]+"$`, etag)
+	s.deps.board.AddPost(tumblr.Post{ID: 4, Title: "New"})
+	assert.NotEqual(s.T(), etag, boardETag(s.deps.board))
+}
+
+func TestETagMatches(t *testing.T) {
+	assert.True(t, etagMatches(`W/"1-a"`, `W/"1-a"`))
+	assert.True(t, etagMatches(`"1-a"`, `W/"1-a"`))
+	assert.True(t, etagMatches(`"0-b", W/"1-a"`, `W/"1-a"`))
+	assert.True(t, etagMatches(`*`, `W/"1-a"`))
+	assert.False(t, etagMatches(`W/"2-a"`, `W/"1-a"`))
+}
+
+func (s *HandlerTestSuite) TestCheckBoardCache() {
+	s.addPosts(chatTestPosts)
+	request, err := http.NewRequest("GET", "/stats.json", nil)
+	assert.NoError(s.T(), err)
+	response := httptest.NewRecorder()
+	statsHandler(response, request, s.deps)
+	assert.Equal(s.T(), http.StatusOK, response.Code)
+	etag := response.Header().Get("ETag")
+	lastModified := response.Header().Get("Last-Modified")
+	assert.Equal(s.T(), boardETag(s.deps.board), etag)
+	assert.NotEmpty(s.T(), lastModified)
+	assert.Equal(s.T(), boardCacheControl, response.Header().Get("Cache-Control"))
+
+	request.Header.Set("If-None-Match", etag)
+	response = httptest.NewRecorder()
+	statsHandler(response, request, s.deps)
+	assert.Equal(s.T(), http.StatusNotModified, response.Code)
+	assert.Empty(s.T(), response.Body.String())
+
+	request.Header.Del("If-None-Match")
+	request.Header.Set("If-Modified-Since", lastModified)
+	response = httptest.NewRecorder()
+	statsHandler(response, request, s.deps)
+	assert.Equal(s.T(), http.StatusNotModified, response.Code)
+
+	s.deps.board.AddPost(tumblr.Post{ID: 4, Title: "New"})
+	request.Header.Del("If-Modified-Since")
+	request.Header.Set("If-None-Match", etag)
+	response = httptest.NewRecorder()
+	statsHandler(response, request, s.deps)
+	assert.Equal(s.T(), http.StatusOK, response.Code)
+	assert.NotEqual(s.T(), etag, response.Header().Get("ETag"))
+}
+
+func (s *HandlerTestSuite) TestCheckBoardCacheHandlers() {
+	s.addPosts(chatTestPosts)
+	etag := boardETag(s.deps.board)
+	handlers := map[string]handlerWithDeps{
+		"/search?query=outage": searchHandler,
+		"/postdata/1":          postDataHandler,
+		"/sitemap.xml":         sitemapHandler,
+	}
+	for path, handler := range handlers {
+		request, err := http.NewRequest("GET", path, nil)
+		assert.NoError(s.T(), err)
+		request.Header.Set("If-None-Match", etag)
+		response := httptest.NewRecorder()
+		handler(response, request, s.deps)
+		assert.Equal(s.T(), http.StatusNotModified, response.Code, path)
+		assert.Equal(s.T(), etag, response.Header().Get("ETag"), path)
+	}
+}
+
+func (s *HandlerTestSuite) TestSearchHandlerRandomNotCached() {
+	s.addPosts(chatTestPosts)
+	request, err := http.NewRequest("GET", "/search", nil)
+	assert.NoError(s.T(), err)
+	request.Header.Set("If-None-Match", boardETag(s.deps.board))
+	response := httptest.NewRecorder()
+	searchHandler(response, request, s.deps)
+	assert.Equal(s.T(), http.StatusOK, response.Code)
+	assert.Equal(s.T(), "no-store", response.Header().Get("Cache-Control"))
+	assert.Empty(s.T(), response.Header().Get("ETag"))
+}
+
+func (s *HandlerTestSuite) TestPostDataHandlerNotFoundNotCached() {
+	s.addPosts(chatTestPosts)
+	request, err := http.NewRequest("GET", "/postdata/404", nil)
+	assert.NoError(s.T(), err)
+	request.Header.Set("If-None-Match", boardETag(s.deps.board))
+	response := httptest.NewRecorder()
+	postDataHandler(response, request, s.deps)
+	assert.Equal(s.T(), http.StatusNotFound, response.Code)
+	assert.Empty(s.T(), response.Header().Get("ETag"))
+	assert.Empty(s.T(), response.Header().Get("Last-Modified"))
+}
+
+func (s *HandlerTestSuite) TestSitemapHandlerNotFoundNotCached() {
+	s.addPosts(chatTestPosts)
+	request, err := http.NewRequest("GET", sitemapChildPath+"missing.xml.gz", nil)
+	assert.NoError(s.T(), err)
+	request.Header.Set("If-None-Match", boardETag(s.deps.board))
+	response := httptest.NewRecorder()
+	sitemapHandler(response, request, s.deps)
+	assert.Equal(s.T(), http.StatusNotFound, response.Code)
+	assert.Empty(s.T(), response.Header().Get("ETag"))
+	assert.Empty(s.T(), response.Header().Get("Cache-Control"))
+}
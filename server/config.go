@@ -0,0 +1,32 @@
+package server
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// readConfig reads a YAML config file into config
+func readConfig(path string, config interface{}) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "Cannot read config %s", path)
+	}
+	err = yaml.Unmarshal(data, config)
+	if err != nil {
+		return errors.Wrapf(err, "Cannot parse config %s", path)
+	}
+	return nil
+}
+
+// loadConfig reads a YAML config into config from the file named by envVar,
+// or from defaultFile in the server directory if envVar is not set
+func loadConfig(envVar, defaultFile string, config interface{}) error {
+	path := os.Getenv(envVar)
+	if path == "" {
+		path = relToAbsPath(defaultFile)
+	}
+	return readConfig(path, config)
+}
@@ -0,0 +1,35 @@
+package server
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadConfig(t *testing.T) {
+	config := robotsConfig{}
+	err := readConfig("testdata/robots.yml", &config)
+	assert.NoError(t, err)
+	assert.Equal(t, config.IndexEnvironments, []string{"production", "staging"})
+
+	err = readConfig("testdata/missing.yml", &config)
+	assert.Error(t, err)
+	err = readConfig("testdata/telegram_inline_query.json", &[]string{})
+	assert.Error(t, err)
+}
+
+func TestLoadConfig(t *testing.T) {
+	defer os.Setenv("TEST_CONFIG", os.Getenv("TEST_CONFIG"))
+	os.Setenv("TEST_CONFIG", "")
+	config := robotsConfig{}
+	err := loadConfig("TEST_CONFIG", "robots.yml", &config)
+	assert.NoError(t, err)
+	assert.Equal(t, config.IndexEnvironments, []string{"production"})
+
+	os.Setenv("TEST_CONFIG", "testdata/robots.yml")
+	config = robotsConfig{}
+	err = loadConfig("TEST_CONFIG", "robots.yml", &config)
+	assert.NoError(t, err)
+	assert.Equal(t, config.IndexEnvironments, []string{"production", "staging"})
+}
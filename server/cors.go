@@ -0,0 +1,173 @@
+package server
+
+import (
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// corsDefaultMethods are the methods allowed by policies that do not list any
+var corsDefaultMethods = []string{http.MethodGet, http.MethodHead}
+
+// corsPolicy is the cross-origin access allowed to a route
+type corsPolicy struct {
+	// AllowedOrigins are origin patterns, where "*" alone allows any origin
+	// and cannot be used with AllowCredentials
+	AllowedOrigins   []string `yaml:"allowedOrigins"`
+	AllowedMethods   []string `yaml:"allowedMethods"`
+	AllowedHeaders   []string `yaml:"allowedHeaders"`
+	ExposedHeaders   []string `yaml:"exposedHeaders"`
+	AllowCredentials bool     `yaml:"allowCredentials"`
+	// MaxAge is how many seconds browsers may cache preflight responses
+	MaxAge int `yaml:"maxAge"`
+}
+
+// corsConfig is the CORS policy of each route
+type corsConfig struct {
+	Default corsPolicy            `yaml:"default"`
+	Routes  map[string]corsPolicy `yaml:"routes"`
+}
+
+// validate returns an error if any allowed origin is not a valid pattern, or
+// if a policy allows credentials from any origin, which would let every site
+// make authenticated requests
+func (c corsConfig) validate() error {
+	policies := map[string]corsPolicy{"default": c.Default}
+	for route, policy := range c.Routes {
+		policies[route] = policy
+	}
+	for route, policy := range policies {
+		for _, origin := range policy.AllowedOrigins {
+			if _, err := path.Match(origin, ""); err != nil {
+				return errors.Wrapf(err, "Cannot parse CORS origin %s", origin)
+			}
+			if origin == "*" && policy.AllowCredentials {
+				return errors.Errorf("CORS policy for %s cannot allow credentials from any origin", route)
+			}
+		}
+	}
+	return nil
+}
+
+// loadCORSConfig returns the CORS config, or an error if it cannot be
+// loaded or is invalid
+func loadCORSConfig() (corsConfig, error) {
+	config := corsConfig{}
+	err := loadConfig("CORS_CONFIG", "cors.yml", &config)
+	if err != nil {
+		return config, err
+	}
+	return config, config.validate()
+}
+
+// policy returns the CORS policy of a route
+func (c corsConfig) policy(route string) corsPolicy {
+	policy, ok := c.Routes[route]
+	if !ok {
+		policy = c.Default
+	}
+	return policy
+}
+
+// allowsOrigin returns whether the policy allows requests from an origin
+func (p corsPolicy) allowsOrigin(origin string) bool {
+	for _, pattern := range p.AllowedOrigins {
+		if pattern == "*" {
+			return true
+		}
+		if matched, _ := path.Match(pattern, origin); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// methods returns the methods allowed by the policy
+func (p corsPolicy) methods() []string {
+	if len(p.AllowedMethods) == 0 {
+		return corsDefaultMethods
+	}
+	return p.AllowedMethods
+}
+
+// allowsMethod returns whether the policy allows a request method
+func (p corsPolicy) allowsMethod(method string) bool {
+	for _, allowed := range p.methods() {
+		if strings.EqualFold(allowed, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsHeaders returns whether the policy allows every header in a
+// comma separated Access-Control-Request-Headers list
+func (p corsPolicy) allowsHeaders(headers string) bool {
+	for _, header := range strings.Split(headers, ",") {
+		header = strings.TrimSpace(header)
+		if header == "" {
+			continue
+		}
+		allowed := false
+		for _, allowedHeader := range p.AllowedHeaders {
+			if allowedHeader == "*" || strings.EqualFold(allowedHeader, header) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+// handle adds the CORS headers of a route to a response, and answers
+// preflight requests. It returns true if the request was a preflight request
+// that has been answered.
+func (c corsConfig) handle(w http.ResponseWriter, r *http.Request, route string) bool {
+	policy := c.policy(route)
+	if len(policy.AllowedOrigins) == 0 {
+		return false
+	}
+	header := w.Header()
+	header.Add("Vary", "Origin")
+	origin := r.Header.Get("Origin")
+	requestMethod := r.Header.Get("Access-Control-Request-Method")
+	preflight := r.Method == http.MethodOptions && origin != "" && requestMethod != ""
+	if preflight {
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+	}
+	if origin == "" || !policy.allowsOrigin(origin) {
+		if preflight {
+			w.WriteHeader(http.StatusNoContent)
+		}
+		return preflight
+	}
+	header.Set("Access-Control-Allow-Origin", origin)
+	if policy.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if !preflight {
+		if len(policy.ExposedHeaders) > 0 {
+			header.Set("Access-Control-Expose-Headers", strings.Join(policy.ExposedHeaders, ", "))
+		}
+		return false
+	}
+	requestHeaders := r.Header.Get("Access-Control-Request-Headers")
+	if policy.allowsMethod(requestMethod) && policy.allowsHeaders(requestHeaders) {
+		header.Set("Access-Control-Allow-Methods", strings.Join(policy.methods(), ", "))
+		if requestHeaders != "" {
+			header.Set("Access-Control-Allow-Headers", requestHeaders)
+		}
+		if policy.MaxAge > 0 {
+			header.Set("Access-Control-Max-Age", strconv.Itoa(policy.MaxAge))
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
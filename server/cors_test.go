@@ -0,0 +1,155 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/albertyw/reaction-pics/tumblr"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func corsTestConfig(t *testing.T) corsConfig {
+	config := corsConfig{}
+	err := readConfig("testdata/cors.yml", &config)
+	assert.NoError(t, err)
+	return config
+}
+
+func corsRequest(method, origin string, headers map[string]string) *http.Request {
+	request := httptest.NewRequest(method, "/search", nil)
+	if origin != "" {
+		request.Header.Set("Origin", origin)
+	}
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+	return request
+}
+
+func TestReadCORSConfig(t *testing.T) {
+	config := corsConfig{}
+	err := readConfig(relToAbsPath("cors.yml"), &config)
+	assert.NoError(t, err)
+	assert.NoError(t, config.validate())
+	assert.Equal(t, []string{"*"}, config.policy("/postdata/").AllowedOrigins)
+	assert.Empty(t, config.policy("/admin/links").AllowedOrigins)
+
+	assert.Error(t, corsConfig{Default: corsPolicy{AllowedOrigins: []string{"https://["}}}.validate())
+	credentials := corsPolicy{AllowedOrigins: []string{"*"}, AllowCredentials: true}
+	assert.Error(t, corsConfig{Routes: map[string]corsPolicy{"/search": credentials}}.validate())
+	credentials.AllowedOrigins = []string{"https://*.example.com"}
+	assert.NoError(t, corsConfig{Routes: map[string]corsPolicy{"/search": credentials}}.validate())
+}
+
+func TestLoadCORSConfig(t *testing.T) {
+	defer os.Setenv("CORS_CONFIG", os.Getenv("CORS_CONFIG"))
+	os.Setenv("CORS_CONFIG", "testdata/cors.yml")
+	config, err := loadCORSConfig()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, config.Routes)
+	os.Setenv("CORS_CONFIG", "testdata/missing.yml")
+	_, err = loadCORSConfig()
+	assert.Error(t, err)
+	os.Setenv("CORS_CONFIG", "testdata/cors_invalid.yml")
+	_, err = loadCORSConfig()
+	assert.Error(t, err)
+}
+
+func TestCORSPolicy(t *testing.T) {
+	config := corsTestConfig(t)
+	policy := config.policy("/postdata/")
+	assert.True(t, policy.allowsOrigin("https://tools.example.com"))
+	assert.False(t, policy.allowsOrigin("https://example.com"))
+	assert.False(t, policy.allowsOrigin("https://tools.example.com.evil.com"))
+	assert.True(t, policy.allowsMethod("get"))
+	assert.False(t, policy.allowsMethod("POST"))
+
+	policy = config.policy("/graphql")
+	assert.True(t, policy.allowsOrigin("chrome-extension://abcdef"))
+	assert.True(t, policy.allowsHeaders("content-type"))
+	assert.True(t, policy.allowsHeaders(""))
+	assert.False(t, policy.allowsHeaders("Content-Type, X-Custom"))
+}
+
+func TestCORSHandleRequest(t *testing.T) {
+	config := corsTestConfig(t)
+	response := httptest.NewRecorder()
+	assert.False(t, config.handle(response, corsRequest("GET", "https://a.com", nil), "/search"))
+	assert.Equal(t, "https://a.com", response.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "ETag", response.Header().Get("Access-Control-Expose-Headers"))
+	assert.Empty(t, response.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "Origin", response.Header().Get("Vary"))
+
+	response = httptest.NewRecorder()
+	assert.False(t, config.handle(response, corsRequest("GET", "https://a.example.com", nil), "/stats.json"))
+	assert.Equal(t, "https://a.example.com", response.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", response.Header().Get("Access-Control-Allow-Credentials"))
+
+	response = httptest.NewRecorder()
+	assert.False(t, config.handle(response, corsRequest("GET", "https://a.com", nil), "/stats.json"))
+	assert.Empty(t, response.Header().Get("Access-Control-Allow-Origin"))
+
+	response = httptest.NewRecorder()
+	assert.False(t, config.handle(response, corsRequest("GET", "https://a.example.com", nil), "/admin/links"))
+	assert.Empty(t, response.Header())
+}
+
+func TestCORSHandlePreflight(t *testing.T) {
+	config := corsTestConfig(t)
+	headers := map[string]string{
+		"Access-Control-Request-Method":  "POST",
+		"Access-Control-Request-Headers": "content-type",
+	}
+	response := httptest.NewRecorder()
+	assert.True(t, config.handle(response, corsRequest("OPTIONS", "chrome-extension://abc", headers), "/graphql"))
+	assert.Equal(t, http.StatusNoContent, response.Code)
+	assert.Equal(t, "chrome-extension://abc", response.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, POST", response.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "content-type", response.Header().Get("Access-Control-Allow-Headers"))
+	assert.Empty(t, response.Header().Get("Access-Control-Max-Age"))
+
+	response = httptest.NewRecorder()
+	assert.True(t, config.handle(response, corsRequest("OPTIONS", "https://a.com", headers), "/search"))
+	assert.Equal(t, http.StatusNoContent, response.Code)
+	assert.Equal(t, "https://a.com", response.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, response.Header().Get("Access-Control-Allow-Methods"))
+
+	response = httptest.NewRecorder()
+	headers = map[string]string{"Access-Control-Request-Method": "GET"}
+	assert.True(t, config.handle(response, corsRequest("OPTIONS", "https://a.com", headers), "/search"))
+	assert.Equal(t, "GET, HEAD", response.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "600", response.Header().Get("Access-Control-Max-Age"))
+
+	response = httptest.NewRecorder()
+	assert.True(t, config.handle(response, corsRequest("OPTIONS", "https://a.com", headers), "/graphql"))
+	assert.Equal(t, http.StatusNoContent, response.Code)
+	assert.Empty(t, response.Header().Get("Access-Control-Allow-Origin"))
+
+	response = httptest.NewRecorder()
+	assert.False(t, config.handle(response, corsRequest("OPTIONS", "", headers), "/search"))
+}
+
+func TestCORSHandlerGenerator(t *testing.T) {
+	board := tumblr.NewBoard([]tumblr.Post{})
+	generator := handlerGenerator{
+		logger: zap.NewNop().Sugar(),
+		deps:   handlerDeps{logger: zap.NewNop().Sugar(), board: &board},
+		cors:   corsTestConfig(t),
+	}
+	called := false
+	_, handler := generator.newHandler("/search", func(w http.ResponseWriter, r *http.Request, d handlerDeps) {
+		called = true
+	})
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, corsRequest("OPTIONS", "https://a.com", map[string]string{"Access-Control-Request-Method": "GET"}))
+	assert.Equal(t, http.StatusNoContent, response.Code)
+	assert.False(t, called)
+
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, corsRequest("GET", "https://a.com", nil))
+	assert.True(t, called)
+	assert.Equal(t, "https://a.com", response.Header().Get("Access-Control-Allow-Origin"))
+}
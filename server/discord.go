@@ -0,0 +1,146 @@
+package server
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/rollbar/rollbar-go"
+)
+
+// Discord interaction and interaction response types
+const (
+	discordInteractionPing               = 1
+	discordInteractionApplicationCommand = 2
+	discordResponsePong                  = 1
+	discordResponseChannelMessage        = 4
+	discordFlagEphemeral                 = 64
+	discordCommand                       = "reaction"
+	discordQueryOption                   = "query"
+)
+
+// verifyDiscordRequest checks the Ed25519 signature that Discord sends with
+// the timestamp and body of a request
+func verifyDiscordRequest(header http.Header, body []byte, publicKeyHex string) error {
+	publicKey, err := hex.DecodeString(publicKeyHex)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return errors.New("Discord public key is not configured")
+	}
+	signature, err := hex.DecodeString(header.Get("X-Signature-Ed25519"))
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return errors.New("Cannot parse Discord signature")
+	}
+	message := append([]byte(header.Get("X-Signature-Timestamp")), body...)
+	if !ed25519.Verify(ed25519.PublicKey(publicKey), message, signature) {
+		return errors.New("Discord signature does not match")
+	}
+	return nil
+}
+
+// discordInteraction is the part of a Discord interaction that is used for
+// answering commands
+type discordInteraction struct {
+	Type int `json:"type"`
+	Data struct {
+		Name    string `json:"name"`
+		Options []struct {
+			Name  string      `json:"name"`
+			Value interface{} `json:"value"`
+		} `json:"options"`
+	} `json:"data"`
+}
+
+// query returns the value of the query option of a command
+func (i discordInteraction) query() string {
+	for _, option := range i.Data.Options {
+		if option.Name == discordQueryOption {
+			value, _ := option.Value.(string)
+			return value
+		}
+	}
+	return ""
+}
+
+type discordImage struct {
+	URL string `json:"url"`
+}
+
+type discordEmbed struct {
+	Title string        `json:"title"`
+	URL   string        `json:"url"`
+	Image *discordImage `json:"image,omitempty"`
+}
+
+type discordMessage struct {
+	Content string         `json:"content,omitempty"`
+	Embeds  []discordEmbed `json:"embeds,omitempty"`
+	Flags   int            `json:"flags,omitempty"`
+}
+
+type discordResponse struct {
+	Type int             `json:"type"`
+	Data *discordMessage `json:"data,omitempty"`
+}
+
+// discordReply returns a message with an embed of a post, or an ephemeral
+// message if there is no post
+func discordReply(reply chatReply) discordMessage {
+	if !reply.Found() {
+		return discordMessage{
+			Content: reply.NotFoundText(),
+			Flags:   discordFlagEphemeral,
+		}
+	}
+	return discordMessage{
+		Embeds: []discordEmbed{{
+			Title: reply.Title(),
+			URL:   reply.PostURL(),
+			Image: &discordImage{URL: reply.ImageURL()},
+		}},
+	}
+}
+
+// discordHandler answers Discord pings and reaction application commands
+func discordHandler(w http.ResponseWriter, r *http.Request, d handlerDeps) {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	if err == nil {
+		err = verifyDiscordRequest(r.Header, body, os.Getenv("DISCORD_PUBLIC_KEY"))
+	}
+	if err != nil {
+		d.logger.Warn(err)
+		rollbarRequestError(rollbar.WARN, r, err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	interaction := discordInteraction{}
+	err = json.Unmarshal(body, &interaction)
+	if err != nil {
+		err = errors.Wrap(err, "Cannot parse Discord interaction")
+		d.logger.Warn(err)
+		rollbarRequestError(rollbar.WARN, r, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var response discordResponse
+	switch interaction.Type {
+	case discordInteractionPing:
+		response = discordResponse{Type: discordResponsePong}
+	case discordInteractionApplicationCommand:
+		if interaction.Data.Name != discordCommand {
+			http.Error(w, "Unknown command", http.StatusBadRequest)
+			return
+		}
+		message := discordReply(newChatReply(d.board, interaction.query()))
+		response = discordResponse{Type: discordResponseChannelMessage, Data: &message}
+	default:
+		http.Error(w, "Unsupported interaction type", http.StatusBadRequest)
+		return
+	}
+	data, _ := json.Marshal(response)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func discordRequest(t *testing.T, privateKey ed25519.PrivateKey, body []byte) *http.Request {
+	request, err := http.NewRequest("POST", "/integrations/discord", bytes.NewReader(body))
+	assert.NoError(t, err)
+	timestamp := "1600000000"
+	signature := ed25519.Sign(privateKey, append([]byte(timestamp), body...))
+	request.Header.Set("X-Signature-Ed25519", hex.EncodeToString(signature))
+	request.Header.Set("X-Signature-Timestamp", timestamp)
+	return request
+}
+
+func setDiscordKey(t *testing.T) (ed25519.PrivateKey, func()) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+	origKey := os.Getenv("DISCORD_PUBLIC_KEY")
+	os.Setenv("DISCORD_PUBLIC_KEY", hex.EncodeToString(publicKey))
+	return privateKey, func() { os.Setenv("DISCORD_PUBLIC_KEY", origKey) }
+}
+
+func TestVerifyDiscordRequest(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+	publicKeyHex := hex.EncodeToString(publicKey)
+	body := []byte(`{"type":1}`)
+	request := discordRequest(t, privateKey, body)
+
+	assert.NoError(t, verifyDiscordRequest(request.Header, body, publicKeyHex))
+	assert.Error(t, verifyDiscordRequest(request.Header, body, ""))
+	assert.Error(t, verifyDiscordRequest(request.Header, []byte(`{"type":2}`), publicKeyHex))
+	request.Header.Set("X-Signature-Timestamp", "1600000001")
+	assert.Error(t, verifyDiscordRequest(request.Header, body, publicKeyHex))
+	request.Header.Set("X-Signature-Ed25519", "asdf")
+	assert.Error(t, verifyDiscordRequest(request.Header, body, publicKeyHex))
+}
+
+func (s *HandlerTestSuite) TestDiscordHandlerPing() {
+	s.addPosts(chatTestPosts)
+	privateKey, reset := setDiscordKey(s.T())
+	defer reset()
+
+	response := httptest.NewRecorder()
+	discordHandler(response, discordRequest(s.T(), privateKey, []byte(`{"type":1}`)), s.deps)
+	assert.Equal(s.T(), response.Code, 200)
+	assert.Equal(s.T(), response.Body.String(), `{"type":1}`)
+}
+
+func (s *HandlerTestSuite) TestDiscordHandlerCommand() {
+	s.addPosts(chatTestPosts)
+	privateKey, reset := setDiscordKey(s.T())
+	defer reset()
+	body, err := ioutil.ReadFile("testdata/discord_command.json")
+	assert.NoError(s.T(), err)
+
+	response := httptest.NewRecorder()
+	discordHandler(response, discordRequest(s.T(), privateKey, body), s.deps)
+	assert.Equal(s.T(), response.Code, 200)
+	data := discordResponse{}
+	assert.NoError(s.T(), json.Unmarshal(response.Body.Bytes(), &data))
+	assert.Equal(s.T(), data.Type, discordResponseChannelMessage)
+	embed := data.Data.Embeds[0]
+	assert.Equal(s.T(), embed.Title, "Outage one")
+	assert.Equal(s.T(), embed.URL, os.Getenv("HOST")+"/post/1/outage-one")
+	assert.Equal(s.T(), embed.Image.URL, "https://example.com/1.gif")
+}
+
+func (s *HandlerTestSuite) TestDiscordHandlerNoResults() {
+	s.addPosts(chatTestPosts)
+	privateKey, reset := setDiscordKey(s.T())
+	defer reset()
+	body := []byte(`{"type":2,"data":{"name":"reaction","options":[{"name":"query","value":"asdf"}]}}`)
+
+	response := httptest.NewRecorder()
+	discordHandler(response, discordRequest(s.T(), privateKey, body), s.deps)
+	data := discordResponse{}
+	assert.NoError(s.T(), json.Unmarshal(response.Body.Bytes(), &data))
+	assert.Equal(s.T(), data.Data.Flags, discordFlagEphemeral)
+	assert.Contains(s.T(), data.Data.Content, "No reactions found")
+}
+
+func (s *HandlerTestSuite) TestDiscordHandlerUnknownCommand() {
+	s.addPosts(chatTestPosts)
+	privateKey, reset := setDiscordKey(s.T())
+	defer reset()
+	body := []byte(`{"type":2,"data":{"name":"other","options":[{"name":"query","value":"outage"}]}}`)
+
+	response := httptest.NewRecorder()
+	discordHandler(response, discordRequest(s.T(), privateKey, body), s.deps)
+	assert.Equal(s.T(), response.Code, http.StatusBadRequest)
+	assert.Contains(s.T(), response.Body.String(), "Unknown command")
+}
+
+func (s *HandlerTestSuite) TestDiscordHandlerTooLarge() {
+	s.addPosts(chatTestPosts)
+	privateKey, reset := setDiscordKey(s.T())
+	defer reset()
+	body := []byte(`{"type":1,"padding":"` + strings.Repeat("a", maxRequestBodySize) + `"}`)
+
+	response := httptest.NewRecorder()
+	discordHandler(response, discordRequest(s.T(), privateKey, body), s.deps)
+	assert.Equal(s.T(), response.Code, 401)
+}
+
+func (s *HandlerTestSuite) TestDiscordHandlerUnsigned() {
+	s.addPosts(chatTestPosts)
+	_, reset := setDiscordKey(s.T())
+	defer reset()
+	_, otherKey, err := ed25519.GenerateKey(nil)
+	assert.NoError(s.T(), err)
+
+	response := httptest.NewRecorder()
+	discordHandler(response, discordRequest(s.T(), otherKey, []byte(`{"type":1}`)), s.deps)
+	assert.Equal(s.T(), response.Code, 401)
+}
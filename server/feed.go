@@ -0,0 +1,137 @@
+package server
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/albertyw/reaction-pics/tumblr"
+	"github.com/gorilla/feeds"
+	"github.com/pkg/errors"
+	"github.com/rollbar/rollbar-go"
+)
+
+const (
+	feedSize   = 50
+	feedNewest = "newest"
+	feedTop    = "top"
+)
+
+// feedPosts returns the newest or top liked posts matching a query
+func feedPosts(d handlerDeps, query, sort string) []tumblr.Post {
+	query = strings.ToLower(query)
+	key := searchKey{query: query, sort: sort, limit: feedSize}
+	posts, _ := d.searches.get(d.board, key, func() ([]tumblr.Post, int) {
+		queriedBoard := d.board.FilterBoard(query)
+		switch sort {
+		case feedTop:
+			queriedBoard.SortPostsByLikes()
+		default:
+			queriedBoard.SortPostsByNewest()
+		}
+		total := len(queriedBoard.Posts)
+		queriedBoard.LimitBoard(0, feedSize)
+		return queriedBoard.Posts, total
+	})
+	return posts
+}
+
+// feedEnclosure returns an enclosure of a post image, using the indexed
+// image metadata if it is available
+func feedEnclosure(post tumblr.Post) *feeds.Enclosure {
+	enclosure := &feeds.Enclosure{
+		Url:    post.Image,
+		Length: "0",
+		Type:   mime.TypeByExtension(path.Ext(post.Image)),
+	}
+	if post.Meta != nil {
+		enclosure.Length = strconv.FormatInt(post.Meta.Size, 10)
+		enclosure.Type = post.Meta.MimeType
+	}
+	if enclosure.Type == "" {
+		enclosure.Type = "application/octet-stream"
+	}
+	return enclosure
+}
+
+// newFeed returns a feed of posts for a query sorted by newest or top liked.
+// Posts without a timestamp and the feed itself are dated by when the board
+// last changed so that the feed only changes with the board.
+func newFeed(d handlerDeps, query, sort string) *feeds.Feed {
+	host := os.Getenv("HOST")
+	link := host + "/"
+	title := siteName
+	if sort == feedTop {
+		title = "Top " + title
+	}
+	if query != "" {
+		title = fmt.Sprintf("%s: %s", title, query)
+		link += "?" + url.Values{"query": {query}}.Encode()
+	}
+	feed := &feeds.Feed{
+		Title:       title,
+		Link:        &feeds.Link{Href: link},
+		Description: "Search various programming memes",
+		Id:          link,
+		Created:     d.board.Updated(),
+	}
+	for _, post := range feedPosts(d, query, sort) {
+		permalink := host + post.InternalURL()
+		item := &feeds.Item{
+			Title:       post.Title,
+			Link:        &feeds.Link{Href: permalink},
+			Id:          permalink,
+			Description: post.Title,
+			Enclosure:   feedEnclosure(post),
+			Created:     feed.Created,
+		}
+		if post.Timestamp > 0 {
+			item.Created = time.Unix(post.Timestamp, 0).UTC()
+		}
+		feed.Add(item)
+	}
+	if sort != feedTop && len(feed.Items) > 0 {
+		feed.Created = feed.Items[0].Created
+	}
+	return feed
+}
+
+// feedHandler returns an http handler that returns a feed of the newest or
+// top liked posts in RSS, Atom, or JSON Feed format
+func feedHandler(format string) handlerWithDeps {
+	return func(w http.ResponseWriter, r *http.Request, d handlerDeps) {
+		params := r.URL.Query()
+		sort := params.Get("sort")
+		if sort != feedTop {
+			sort = feedNewest
+		}
+		feed := newFeed(d, params.Get("query"), sort)
+		var data string
+		var err error
+		switch format {
+		case "rss":
+			w.Header().Set("Content-Type", "application/rss+xml")
+			data, err = feed.ToRss()
+		case "atom":
+			w.Header().Set("Content-Type", "application/atom+xml")
+			data, err = feed.ToAtom()
+		case "json":
+			w.Header().Set("Content-Type", "application/feed+json")
+			data, err = feed.ToJSON()
+		}
+		if err != nil {
+			err = errors.Wrap(err, "Cannot generate feed")
+			d.logger.Error(err)
+			rollbarRequestError(rollbar.ERR, r, err)
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		fmt.Fprint(w, data)
+	}
+}
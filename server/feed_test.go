@@ -0,0 +1,159 @@
+package server
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/albertyw/reaction-pics/tumblr"
+	"github.com/stretchr/testify/assert"
+)
+
+var feedTestPosts = []tumblr.Post{
+	{ID: 1, Title: "Outage old", Image: "https://example.com/1.gif", Likes: 5, Timestamp: 1500000000},
+	{ID: 2, Title: "Outage new", Image: "https://example.com/2.png", Likes: 1, Timestamp: 1600000000},
+	{
+		ID:    3,
+		Title: "Deploy",
+		Image: "https://example.com/3.gif",
+		Likes: 3,
+		Meta:  &tumblr.ImageMeta{Size: 34567, MimeType: "image/gif"},
+	},
+}
+
+func (s *HandlerTestSuite) TestFeedPosts() {
+	s.addPosts(feedTestPosts)
+	posts := feedPosts(s.deps, "", feedNewest)
+	assert.Equal(s.T(), []int64{posts[0].ID, posts[1].ID, posts[2].ID}, []int64{2, 1, 3})
+	posts = feedPosts(s.deps, "", feedTop)
+	assert.Equal(s.T(), []int64{posts[0].ID, posts[1].ID, posts[2].ID}, []int64{1, 3, 2})
+	posts = feedPosts(s.deps, "outage", feedTop)
+	assert.Equal(s.T(), len(posts), 2)
+	posts = feedPosts(s.deps, "OutAge", feedTop)
+	assert.Equal(s.T(), len(posts), 2)
+}
+
+func (s *HandlerTestSuite) TestNewFeedDates() {
+	s.addPosts(feedTestPosts)
+	feed := newFeed(s.deps, "", feedTop)
+	assert.Equal(s.T(), feed.Created, s.deps.board.Updated())
+	assert.Equal(s.T(), feed.Items[0].Created, time.Unix(1500000000, 0).UTC())
+	assert.Equal(s.T(), feed.Items[1].Title, "Deploy")
+	assert.Equal(s.T(), feed.Items[1].Created, s.deps.board.Updated())
+
+	feed = newFeed(s.deps, "", feedNewest)
+	assert.Equal(s.T(), feed.Created, time.Unix(1600000000, 0).UTC())
+
+	first, err := newFeed(s.deps, "", feedTop).ToAtom()
+	assert.NoError(s.T(), err)
+	second, err := newFeed(s.deps, "", feedTop).ToAtom()
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), first, second)
+}
+
+func TestFeedEnclosure(t *testing.T) {
+	enclosure := feedEnclosure(tumblr.Post{Image: "https://example.com/1.gif"})
+	assert.Equal(t, enclosure.Url, "https://example.com/1.gif")
+	assert.Equal(t, enclosure.Type, "image/gif")
+	assert.Equal(t, enclosure.Length, "0")
+
+	enclosure = feedEnclosure(tumblr.Post{Image: "https://example.com/1", Meta: &tumblr.ImageMeta{Size: 12, MimeType: "image/png"}})
+	assert.Equal(t, enclosure.Type, "image/png")
+	assert.Equal(t, enclosure.Length, "12")
+
+	enclosure = feedEnclosure(tumblr.Post{Image: "https://example.com/1"})
+	assert.Equal(t, enclosure.Type, "application/octet-stream")
+}
+
+func (s *HandlerTestSuite) TestFeedHandlerRSS() {
+	s.addPosts(feedTestPosts)
+	request, err := http.NewRequest("GET", "/feed.rss?query=outage", nil)
+	assert.NoError(s.T(), err)
+
+	response := httptest.NewRecorder()
+	feedHandler("rss")(response, request, s.deps)
+	assert.Equal(s.T(), response.Code, 200)
+	assert.Equal(s.T(), response.Header().Get("Content-Type"), "application/rss+xml")
+	data := struct {
+		Channel struct {
+			Title string `xml:"title"`
+			Items []struct {
+				Title     string `xml:"title"`
+				Link      string `xml:"link"`
+				Enclosure struct {
+					URL    string `xml:"url,attr"`
+					Length string `xml:"length,attr"`
+					Type   string `xml:"type,attr"`
+				} `xml:"enclosure"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}{}
+	assert.NoError(s.T(), xml.Unmarshal(response.Body.Bytes(), &data))
+	assert.Equal(s.T(), data.Channel.Title, "Reaction Pics: outage")
+	assert.Equal(s.T(), len(data.Channel.Items), 2)
+	item := data.Channel.Items[0]
+	assert.Equal(s.T(), item.Title, "Outage new")
+	assert.Equal(s.T(), item.Link, os.Getenv("HOST")+"/post/2/outage-new")
+	assert.Equal(s.T(), item.Enclosure.URL, "https://example.com/2.png")
+	assert.Equal(s.T(), item.Enclosure.Type, "image/png")
+}
+
+func (s *HandlerTestSuite) TestFeedHandlerAtom() {
+	s.addPosts(feedTestPosts)
+	request, err := http.NewRequest("GET", "/feed.atom?sort=top", nil)
+	assert.NoError(s.T(), err)
+
+	response := httptest.NewRecorder()
+	feedHandler("atom")(response, request, s.deps)
+	assert.Equal(s.T(), response.Code, 200)
+	assert.Equal(s.T(), response.Header().Get("Content-Type"), "application/atom+xml")
+	data := struct {
+		Title   string `xml:"title"`
+		Entries []struct {
+			Title string `xml:"title"`
+			Links []struct {
+				Href string `xml:"href,attr"`
+				Rel  string `xml:"rel,attr"`
+			} `xml:"link"`
+		} `xml:"entry"`
+	}{}
+	assert.NoError(s.T(), xml.Unmarshal(response.Body.Bytes(), &data))
+	assert.Equal(s.T(), data.Title, "Top Reaction Pics")
+	assert.Equal(s.T(), len(data.Entries), 3)
+	entry := data.Entries[0]
+	assert.Equal(s.T(), entry.Title, "Outage old")
+	assert.Equal(s.T(), entry.Links[0].Href, os.Getenv("HOST")+"/post/1/outage-old")
+	assert.Equal(s.T(), entry.Links[1].Rel, "enclosure")
+	assert.Equal(s.T(), entry.Links[1].Href, "https://example.com/1.gif")
+}
+
+func (s *HandlerTestSuite) TestFeedHandlerJSON() {
+	s.addPosts(feedTestPosts)
+	request, err := http.NewRequest("GET", "/feed.json", nil)
+	assert.NoError(s.T(), err)
+
+	response := httptest.NewRecorder()
+	feedHandler("json")(response, request, s.deps)
+	assert.Equal(s.T(), response.Code, 200)
+	assert.Equal(s.T(), response.Header().Get("Content-Type"), "application/feed+json")
+	data := struct {
+		Version string `json:"version"`
+		Items   []struct {
+			ID    string `json:"id"`
+			URL   string `json:"url"`
+			Title string `json:"title"`
+			Image string `json:"image"`
+		} `json:"items"`
+	}{}
+	assert.NoError(s.T(), json.Unmarshal(response.Body.Bytes(), &data))
+	assert.Contains(s.T(), data.Version, "jsonfeed.org")
+	assert.Equal(s.T(), len(data.Items), 3)
+	item := data.Items[0]
+	assert.Equal(s.T(), item.Title, "Outage new")
+	assert.Equal(s.T(), item.URL, os.Getenv("HOST")+"/post/2/outage-new")
+	assert.Equal(s.T(), item.Image, "https://example.com/2.png")
+}
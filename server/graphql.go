@@ -0,0 +1,303 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/albertyw/reaction-pics/tumblr"
+	"github.com/graphql-go/graphql"
+	"github.com/pkg/errors"
+	"github.com/rollbar/rollbar-go"
+)
+
+const (
+	graphqlMaxLimit       = 100
+	graphqlRelatedDefault = 5
+)
+
+// graphqlDepsKey is the context key of the handler dependencies that queries
+// resolve against
+type graphqlDepsKey struct{}
+
+// graphqlDeps returns the handler dependencies of a query
+func graphqlDeps(p graphql.ResolveParams) handlerDeps {
+	return p.Context.Value(graphqlDepsKey{}).(handlerDeps)
+}
+
+// graphqlBoard returns the board of a query
+func graphqlBoard(p graphql.ResolveParams) *tumblr.Board {
+	return graphqlDeps(p).board
+}
+
+// graphqlLimit returns the limit argument of a field, capped at graphqlMaxLimit
+func graphqlLimit(p graphql.ResolveParams, defaultLimit int) int {
+	limit, ok := p.Args["limit"].(int)
+	if !ok || limit < 0 {
+		limit = defaultLimit
+	}
+	if limit > graphqlMaxLimit {
+		limit = graphqlMaxLimit
+	}
+	return limit
+}
+
+var graphqlImageType = graphql.NewEnum(graphql.EnumConfig{
+	Name: "ImageType",
+	Values: graphql.EnumValueConfigMap{
+		"STATIC":   &graphql.EnumValueConfig{Value: tumblr.ImageTypeStatic},
+		"ANIMATED": &graphql.EnumValueConfig{Value: tumblr.ImageTypeAnimated},
+	},
+})
+
+var graphqlImageMeta = graphql.NewObject(graphql.ObjectConfig{
+	Name: "ImageMeta",
+	Fields: graphql.Fields{
+		"width":    &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"height":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"frames":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"duration": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Animation duration in milliseconds"},
+		"size":     &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "File size in bytes"},
+		"mimeType": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"animated": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Boolean),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*tumblr.ImageMeta).Animated(), nil
+			},
+		},
+	},
+})
+
+var graphqlPost = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Post",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.ID),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return strconv.FormatInt(p.Source.(tumblr.Post).ID, 10), nil
+			},
+		},
+		"title": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"url":   &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "Url of the original post"},
+		"image": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"likes": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"permalink": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return newAPIPost(p.Source.(tumblr.Post)).Permalink, nil
+			},
+		},
+		"tags": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tags := p.Source.(tumblr.Post).Tags
+				if tags == nil {
+					tags = []string{}
+				}
+				return tags, nil
+			},
+		},
+		"timestamp": &graphql.Field{Type: graphql.Int, Description: "Unix time that the post was published"},
+		"meta":      &graphql.Field{Type: graphqlImageMeta},
+	},
+})
+
+func init() {
+	// related is added after declaring Post because its type refers to Post
+	graphqlPost.AddFieldConfig("related", &graphql.Field{
+		Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphqlPost))),
+		Args: graphql.FieldConfigArgument{
+			"limit": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: graphqlRelatedDefault},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			post := p.Source.(tumblr.Post)
+			return graphqlBoard(p).RelatedPosts(post, graphqlLimit(p, graphqlRelatedDefault)), nil
+		},
+	})
+}
+
+var graphqlSearchResult = graphql.NewObject(graphql.ObjectConfig{
+	Name: "SearchResult",
+	Fields: graphql.Fields{
+		"posts":        &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphqlPost)))},
+		"offset":       &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"totalResults": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"hasNext":      &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+	},
+})
+
+var graphqlCacheStats = graphql.NewObject(graphql.ObjectConfig{
+	Name: "CacheStats",
+	Fields: graphql.Fields{
+		"hits":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"misses": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"size":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Number of cached entries"},
+	},
+})
+
+var graphqlStats = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Stats",
+	Fields: graphql.Fields{
+		"searchCache":   &graphql.Field{Type: graphql.NewNonNull(graphqlCacheStats)},
+		"postCount":     &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"staticCount":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"animatedCount": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"version":       &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Increases when posts are added or removed"},
+	},
+})
+
+var graphqlQuery = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Query",
+	Fields: graphql.Fields{
+		"search": &graphql.Field{
+			Type:        graphql.NewNonNull(graphqlSearchResult),
+			Description: "Search posts by title, ranked by likes; an empty query returns random posts",
+			Args: graphql.FieldConfigArgument{
+				"query":       &graphql.ArgumentConfig{Type: graphql.String, DefaultValue: ""},
+				"type":        &graphql.ArgumentConfig{Type: graphqlImageType},
+				"maxDuration": &graphql.ArgumentConfig{Type: graphql.Int, Description: "Maximum animation duration in milliseconds"},
+				"offset":      &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+				"limit":       &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: maxResults},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				filter := tumblr.ImageFilter{}
+				filter.Type, _ = p.Args["type"].(string)
+				filter.MaxDuration, _ = p.Args["maxDuration"].(int)
+				d := graphqlDeps(p)
+				if err := d.board.ValidateImageFilter(filter); err != nil {
+					return nil, err
+				}
+				offset, _ := p.Args["offset"].(int)
+				if offset < 0 {
+					offset = 0
+				}
+				query, _ := p.Args["query"].(string)
+				posts, total := searchPosts(d, query, filter, offset, graphqlLimit(p, maxResults))
+				return map[string]interface{}{
+					"posts":        posts,
+					"offset":       offset,
+					"totalResults": total,
+					"hasNext":      offset+len(posts) < total,
+				}, nil
+			},
+		},
+		"post": &graphql.Field{
+			Type: graphqlPost,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				post, err := findPost(graphqlBoard(p), p.Args["id"].(string))
+				if err != nil {
+					return nil, nil
+				}
+				return *post, nil
+			},
+		},
+		"keywords": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return graphqlBoard(p).Keywords(), nil
+			},
+		},
+		"stats": &graphql.Field{
+			Type: graphql.NewNonNull(graphqlStats),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				d := graphqlDeps(p)
+				board := d.board
+				static := board.FilterBoardByImage(tumblr.ImageFilter{Type: tumblr.ImageTypeStatic})
+				animated := board.FilterBoardByImage(tumblr.ImageFilter{Type: tumblr.ImageTypeAnimated})
+				return map[string]interface{}{
+					"postCount":     board.Len(),
+					"searchCache":   d.searches.stats(),
+					"staticCount":   len(static.Posts),
+					"animatedCount": len(animated.Posts),
+					"version":       board.Version(),
+				}, nil
+			},
+		},
+	},
+})
+
+var (
+	graphqlSchemaOnce sync.Once
+	graphqlSchema     graphql.Schema
+	graphqlSchemaErr  error
+)
+
+// getGraphQLSchema returns the schema of the GraphQL endpoint
+func getGraphQLSchema() (graphql.Schema, error) {
+	graphqlSchemaOnce.Do(func() {
+		graphqlSchema, graphqlSchemaErr = graphql.NewSchema(graphql.SchemaConfig{Query: graphqlQuery})
+	})
+	return graphqlSchema, graphqlSchemaErr
+}
+
+// graphqlRequest is a GraphQL request from a GET query string or POST body
+type graphqlRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// graphqlHandler executes GraphQL queries against the board
+func graphqlHandler(w http.ResponseWriter, r *http.Request, d handlerDeps) {
+	request := graphqlRequest{}
+	switch r.Method {
+	case http.MethodGet:
+		request.Query = r.URL.Query().Get("query")
+		request.OperationName = r.URL.Query().Get("operationName")
+		if variables := r.URL.Query().Get("variables"); variables != "" {
+			err := json.Unmarshal([]byte(variables), &request.Variables)
+			if err != nil {
+				writeGraphQLError(w, http.StatusBadRequest, "Cannot parse GraphQL variables")
+				return
+			}
+		}
+	case http.MethodPost:
+		err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodySize)).Decode(&request)
+		if err != nil {
+			writeGraphQLError(w, http.StatusBadRequest, "Cannot parse GraphQL request")
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeGraphQLError(w, http.StatusMethodNotAllowed, "GraphQL requests must use GET or POST")
+		return
+	}
+	err := checkGraphQLLimits(request.Query, request.Variables, graphqlMaxDepth, graphqlMaxComplexity)
+	if err != nil {
+		writeGraphQLError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	schema, err := getGraphQLSchema()
+	if err != nil {
+		err = errors.Wrap(err, "Cannot build GraphQL schema")
+		d.logger.Error(err)
+		rollbarRequestError(rollbar.ERR, r, err)
+		writeGraphQLError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	result := graphql.Do(graphql.Params{
+		Schema:         schema,
+		RequestString:  request.Query,
+		OperationName:  request.OperationName,
+		VariableValues: request.Variables,
+		Context:        context.WithValue(r.Context(), graphqlDepsKey{}, d),
+	})
+	data, _ := json.Marshal(result)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// writeGraphQLError writes a GraphQL response with a single error
+func writeGraphQLError(w http.ResponseWriter, status int, message string) {
+	data, _ := json.Marshal(map[string]interface{}{
+		"errors": []map[string]string{{"message": message}},
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
@@ -0,0 +1,193 @@
+package server
+
+import (
+	"strconv"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/pkg/errors"
+)
+
+const (
+	graphqlMaxDepth      = 6
+	graphqlMaxComplexity = 5000
+)
+
+// graphqlListDefaults are the default number of items returned by fields
+// that take a limit argument
+var graphqlListDefaults = map[string]int{
+	"search":  maxResults,
+	"related": graphqlRelatedDefault,
+}
+
+// graphqlCost walks the selections of a query to measure its depth and
+// complexity. Each field costs one, and the fields selected under a field
+// with a limit argument are counted once per item the field can return.
+// Fragments are measured once and rejected if they spread themselves.
+type graphqlCost struct {
+	fragments     map[string]*ast.FragmentDefinition
+	variables     map[string]interface{}
+	maxComplexity int
+	costs         map[string]graphqlFragmentCost
+	expanding     map[string]bool
+}
+
+// graphqlFragmentCost is the depth and complexity of a fragment
+type graphqlFragmentCost struct {
+	depth      int
+	complexity int
+}
+
+// limit returns the number of items that a field can return
+func (c *graphqlCost) limit(field *ast.Field) int {
+	limit, ok := graphqlListDefaults[field.Name.Value]
+	if !ok {
+		return 1
+	}
+	for _, argument := range field.Arguments {
+		if argument.Name.Value != "limit" {
+			continue
+		}
+		switch value := argument.Value.(type) {
+		case *ast.IntValue:
+			limit, _ = strconv.Atoi(value.Value)
+		case *ast.Variable:
+			if v, ok := c.variables[value.Name.Value].(float64); ok {
+				limit = int(v)
+			}
+		}
+	}
+	if limit > graphqlMaxLimit {
+		limit = graphqlMaxLimit
+	}
+	if limit < 1 {
+		limit = 1
+	}
+	return limit
+}
+
+// saturate caps a complexity just over the limit so that deeply nested lists
+// cannot overflow
+func (c *graphqlCost) saturate(complexity int) int {
+	if complexity > c.maxComplexity {
+		return c.maxComplexity + 1
+	}
+	return complexity
+}
+
+// selectionSet returns the number of nested field levels and the complexity
+// of a selection set
+func (c *graphqlCost) selectionSet(set *ast.SelectionSet) (int, int, error) {
+	if set == nil {
+		return 0, 0, nil
+	}
+	depth, complexity := 0, 0
+	for _, selection := range set.Selections {
+		var childDepth, childComplexity int
+		var err error
+		switch selection := selection.(type) {
+		case *ast.Field:
+			childDepth, childComplexity, err = c.selectionSet(selection.SelectionSet)
+			childDepth++
+			limit := c.limit(selection)
+			if childComplexity > c.maxComplexity/limit {
+				childComplexity = c.maxComplexity + 1
+			} else {
+				childComplexity = 1 + limit*childComplexity
+			}
+		case *ast.InlineFragment:
+			childDepth, childComplexity, err = c.selectionSet(selection.SelectionSet)
+		case *ast.FragmentSpread:
+			childDepth, childComplexity, err = c.fragment(selection.Name.Value)
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		if childDepth > depth {
+			depth = childDepth
+		}
+		complexity = c.saturate(complexity + childComplexity)
+	}
+	return depth, complexity, nil
+}
+
+// fragment returns the depth and complexity of a named fragment, and an
+// error if the fragment spreads itself directly or through other fragments
+func (c *graphqlCost) fragment(name string) (int, int, error) {
+	if cost, ok := c.costs[name]; ok {
+		return cost.depth, cost.complexity, nil
+	}
+	fragment, ok := c.fragments[name]
+	if !ok {
+		// Unknown fragments are reported by graphql.Do
+		return 0, 0, nil
+	}
+	if c.expanding[name] {
+		return 0, 0, errors.Errorf("Fragment %s cannot spread itself", name)
+	}
+	c.expanding[name] = true
+	depth, complexity, err := c.selectionSet(fragment.SelectionSet)
+	delete(c.expanding, name)
+	if err != nil {
+		return 0, 0, err
+	}
+	c.costs[name] = graphqlFragmentCost{depth: depth, complexity: complexity}
+	return depth, complexity, nil
+}
+
+// operationVariables returns the variables of an operation, using the default
+// value of each variable that was not passed in
+func operationVariables(operation *ast.OperationDefinition, variables map[string]interface{}) map[string]interface{} {
+	values := map[string]interface{}{}
+	for _, definition := range operation.VariableDefinitions {
+		if value, ok := definition.DefaultValue.(*ast.IntValue); ok {
+			if limit, err := strconv.Atoi(value.Value); err == nil {
+				values[definition.Variable.Name.Value] = float64(limit)
+			}
+		}
+	}
+	for name, value := range variables {
+		values[name] = value
+	}
+	return values
+}
+
+// checkGraphQLLimits returns an error if any operation in a query is nested
+// deeper than maxDepth fields or has a complexity over maxComplexity
+func checkGraphQLLimits(query string, variables map[string]interface{}, maxDepth, maxComplexity int) error {
+	document, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		// Syntax errors are reported by graphql.Do
+		return nil
+	}
+	cost := &graphqlCost{
+		fragments:     map[string]*ast.FragmentDefinition{},
+		maxComplexity: maxComplexity,
+		expanding:     map[string]bool{},
+	}
+	for _, definition := range document.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			cost.fragments[fragment.Name.Value] = fragment
+		}
+	}
+	for _, definition := range document.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		// Fragment costs depend on the variables of the operation
+		cost.variables = operationVariables(operation, variables)
+		cost.costs = map[string]graphqlFragmentCost{}
+		depth, complexity, err := cost.selectionSet(operation.SelectionSet)
+		if err != nil {
+			return err
+		}
+		if depth > maxDepth {
+			return errors.Errorf("Query depth exceeds the limit of %d", maxDepth)
+		}
+		if complexity > maxComplexity {
+			return errors.Errorf("Query complexity %d exceeds the limit of %d", complexity, maxComplexity)
+		}
+	}
+	return nil
+}
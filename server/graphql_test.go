@@ -0,0 +1,190 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/albertyw/reaction-pics/tumblr"
+	"github.com/stretchr/testify/assert"
+)
+
+var graphqlTestPosts = []tumblr.Post{
+	{ID: 1, Title: "deploy fails", Likes: 10, Tags: []string{"ops"}, Meta: &tumblr.ImageMeta{Frames: 10, Duration: 500}},
+	{ID: 2, Title: "deploy works", Likes: 5, Tags: []string{"ops"}, Meta: &tumblr.ImageMeta{Frames: 1}},
+	{ID: 3, Title: "coffee", Likes: 1},
+}
+
+func (s *HandlerTestSuite) graphqlPostRequest(query string, variables map[string]interface{}) (int, map[string]interface{}) {
+	body, err := json.Marshal(graphqlRequest{Query: query, Variables: variables})
+	assert.NoError(s.T(), err)
+	request, err := http.NewRequest("POST", "/graphql", bytes.NewReader(body))
+	assert.NoError(s.T(), err)
+	response := httptest.NewRecorder()
+	graphqlHandler(response, request, s.deps)
+	var data map[string]interface{}
+	assert.NoError(s.T(), json.Unmarshal(response.Body.Bytes(), &data))
+	return response.Code, data
+}
+
+func (s *HandlerTestSuite) TestGraphQLSearch() {
+	s.addPosts(graphqlTestPosts)
+	query := `query($q: String) {
+		search(query: $q, type: ANIMATED, limit: 1) {
+			offset totalResults hasNext
+			posts { id title permalink tags meta { duration animated } }
+		}
+	}`
+	code, data := s.graphqlPostRequest(query, map[string]interface{}{"q": "deploy"})
+	assert.Equal(s.T(), http.StatusOK, code)
+	assert.Nil(s.T(), data["errors"])
+	search := data["data"].(map[string]interface{})["search"].(map[string]interface{})
+	assert.Equal(s.T(), float64(1), search["totalResults"])
+	assert.Equal(s.T(), false, search["hasNext"])
+	post := search["posts"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(s.T(), "1", post["id"])
+	assert.Equal(s.T(), "/post/1/deploy-fails", post["permalink"])
+	assert.Equal(s.T(), []interface{}{"ops"}, post["tags"])
+	meta := post["meta"].(map[string]interface{})
+	assert.Equal(s.T(), float64(500), meta["duration"])
+	assert.Equal(s.T(), true, meta["animated"])
+}
+
+func (s *HandlerTestSuite) TestGraphQLSearchInvalidImageFilter() {
+	s.addPosts(graphqlTestPosts)
+	code, data := s.graphqlPostRequest(`{ search(query: "deploy", maxDuration: -1) { totalResults } }`, nil)
+	assert.Equal(s.T(), http.StatusOK, code)
+	assert.Nil(s.T(), data["data"])
+	assert.Contains(s.T(), fmt.Sprint(data["errors"]), "Invalid max duration -1")
+}
+
+func (s *HandlerTestSuite) TestGraphQLPost() {
+	s.addPosts(graphqlTestPosts)
+	code, data := s.graphqlPostRequest(`{ post(id: "1") { title tags related(limit: 2) { id } } missing: post(id: "9") { id } }`, nil)
+	assert.Equal(s.T(), http.StatusOK, code)
+	assert.Nil(s.T(), data["errors"])
+	result := data["data"].(map[string]interface{})
+	post := result["post"].(map[string]interface{})
+	assert.Equal(s.T(), "deploy fails", post["title"])
+	related := post["related"].([]interface{})
+	assert.Len(s.T(), related, 1)
+	assert.Equal(s.T(), "2", related[0].(map[string]interface{})["id"])
+	assert.Nil(s.T(), result["missing"])
+}
+
+func (s *HandlerTestSuite) TestGraphQLKeywordsAndStats() {
+	s.addPosts(graphqlTestPosts)
+	request, err := http.NewRequest("GET", "/graphql?query="+url.QueryEscape(`{ keywords stats { postCount staticCount animatedCount version } }`), nil)
+	assert.NoError(s.T(), err)
+	response := httptest.NewRecorder()
+	graphqlHandler(response, request, s.deps)
+	assert.Equal(s.T(), http.StatusOK, response.Code)
+	assert.Equal(s.T(), "application/json", response.Header().Get("Content-Type"))
+	var data map[string]map[string]interface{}
+	assert.NoError(s.T(), json.Unmarshal(response.Body.Bytes(), &data))
+	stats := data["data"]["stats"].(map[string]interface{})
+	assert.Equal(s.T(), float64(3), stats["postCount"])
+	assert.Equal(s.T(), float64(1), stats["staticCount"])
+	assert.Equal(s.T(), float64(1), stats["animatedCount"])
+	assert.Equal(s.T(), float64(s.deps.board.Version()), stats["version"])
+	assert.NotNil(s.T(), data["data"]["keywords"])
+}
+
+func (s *HandlerTestSuite) TestGraphQLMalformed() {
+	s.addPosts(graphqlTestPosts)
+	request, err := http.NewRequest("POST", "/graphql", bytes.NewReader([]byte("{")))
+	assert.NoError(s.T(), err)
+	response := httptest.NewRecorder()
+	graphqlHandler(response, request, s.deps)
+	assert.Equal(s.T(), http.StatusBadRequest, response.Code)
+
+	code, data := s.graphqlPostRequest(`{ unknown }`, nil)
+	assert.Equal(s.T(), http.StatusOK, code)
+	assert.NotNil(s.T(), data["errors"])
+
+	request, err = http.NewRequest("GET", "/graphql?query=%7B+keywords+%7D&variables=%7B", nil)
+	assert.NoError(s.T(), err)
+	response = httptest.NewRecorder()
+	graphqlHandler(response, request, s.deps)
+	assert.Equal(s.T(), http.StatusBadRequest, response.Code)
+	assert.Contains(s.T(), response.Body.String(), "Cannot parse GraphQL variables")
+
+	body := `{"query":"{ keywords }","operationName":"` + strings.Repeat("a", maxRequestBodySize) + `"}`
+	request, err = http.NewRequest("POST", "/graphql", strings.NewReader(body))
+	assert.NoError(s.T(), err)
+	response = httptest.NewRecorder()
+	graphqlHandler(response, request, s.deps)
+	assert.Equal(s.T(), http.StatusBadRequest, response.Code)
+
+	request, err = http.NewRequest("DELETE", "/graphql", nil)
+	assert.NoError(s.T(), err)
+	response = httptest.NewRecorder()
+	graphqlHandler(response, request, s.deps)
+	assert.Equal(s.T(), http.StatusMethodNotAllowed, response.Code)
+}
+
+func (s *HandlerTestSuite) TestGraphQLLimits() {
+	s.addPosts(graphqlTestPosts)
+	code, data := s.graphqlPostRequest(`{ post(id: "1") { related { related { related { related { related { id } } } } } } }`, nil)
+	assert.Equal(s.T(), http.StatusBadRequest, code)
+	assert.Contains(s.T(), data["errors"].([]interface{})[0].(map[string]interface{})["message"], "depth")
+
+	code, data = s.graphqlPostRequest(`query($n: Int) { search(limit: $n) { posts { related(limit: 100) { id title } } } }`, map[string]interface{}{"n": 100})
+	assert.Equal(s.T(), http.StatusBadRequest, code)
+	assert.Contains(s.T(), data["errors"].([]interface{})[0].(map[string]interface{})["message"], "complexity")
+}
+
+func TestCheckGraphQLLimits(t *testing.T) {
+	query := `query { ...stats search { posts { ...fields } } }
+	fragment stats on Query { stats { postCount } }
+	fragment fields on Post { id title }`
+	assert.NoError(t, checkGraphQLLimits(query, nil, 3, 1000))
+	assert.Error(t, checkGraphQLLimits(query, nil, 2, 1000))
+	// search(1) + 20 * (posts(1) + id(1) + title(1)) + stats(1) + postCount(1) = 63
+	assert.NoError(t, checkGraphQLLimits(query, nil, 3, 63))
+	assert.Error(t, checkGraphQLLimits(query, nil, 3, 62))
+	assert.NoError(t, checkGraphQLLimits("{", nil, 1, 1))
+}
+
+func TestCheckGraphQLLimitsVariableDefaults(t *testing.T) {
+	query := `query($n: Int = 100) { search(limit: $n) { posts { related(limit: $n) { related(limit: $n) { id } } } } }`
+	err := checkGraphQLLimits(query, nil, graphqlMaxDepth, graphqlMaxComplexity)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "complexity")
+	assert.NoError(t, checkGraphQLLimits(query, map[string]interface{}{"n": float64(2)}, graphqlMaxDepth, graphqlMaxComplexity))
+
+	// Each operation is costed with its own variable defaults
+	query = `query A($n: Int = 1) { ...F } query B($n: Int = 100) { ...F }
+	fragment F on Query { search(limit: $n) { posts { related(limit: $n) { related(limit: $n) { id } } } } }`
+	assert.Error(t, checkGraphQLLimits(query, nil, graphqlMaxDepth, graphqlMaxComplexity))
+}
+
+func (s *HandlerTestSuite) TestCheckGraphQLLimitsFragmentCycles() {
+	err := checkGraphQLLimits(`{ ...A } fragment A on Query { ...A }`, nil, graphqlMaxDepth, graphqlMaxComplexity)
+	assert.Error(s.T(), err)
+	err = checkGraphQLLimits(`{ ...A } fragment A on Query { stats { ...B } } fragment B on Stats { ...A }`, nil, graphqlMaxDepth, graphqlMaxComplexity)
+	assert.Error(s.T(), err)
+
+	s.addPosts(graphqlTestPosts)
+	code, data := s.graphqlPostRequest(`{ ...A } fragment A on Query { ...A }`, nil)
+	assert.Equal(s.T(), http.StatusBadRequest, code)
+	assert.Contains(s.T(), data["errors"].([]interface{})[0].(map[string]interface{})["message"], "spread itself")
+}
+
+func TestCheckGraphQLLimitsFragmentFanOut(t *testing.T) {
+	// Each fragment spreads the next twice, which would be 2^40 expansions
+	// without memoizing fragment costs
+	query := "{ ...F0 }"
+	for i := 0; i < 40; i++ {
+		query += fmt.Sprintf(" fragment F%d on Query { ...F%d ...F%d }", i, i+1, i+1)
+	}
+	query += " fragment F40 on Query { keywords }"
+	err := checkGraphQLLimits(query, nil, graphqlMaxDepth, graphqlMaxComplexity)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "complexity")
+}
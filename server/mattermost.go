@@ -0,0 +1,70 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// mattermostAttachment is a Mattermost message attachment
+type mattermostAttachment struct {
+	Fallback  string `json:"fallback"`
+	Title     string `json:"title"`
+	TitleLink string `json:"title_link"`
+	ImageURL  string `json:"image_url"`
+}
+
+// mattermostMessage is a Mattermost message in response to a slash command
+type mattermostMessage struct {
+	ResponseType string                 `json:"response_type"`
+	Text         string                 `json:"text,omitempty"`
+	Attachments  []mattermostAttachment `json:"attachments,omitempty"`
+}
+
+// mattermost answers Mattermost slash commands
+type mattermost struct{}
+
+// verify checks the token that Mattermost sends with a slash command
+func (mattermost) verify(r *http.Request, body []byte) error {
+	token := os.Getenv("MATTERMOST_TOKEN")
+	if token == "" {
+		return errors.New("Mattermost token is not configured")
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return errors.Wrap(err, "Cannot parse Mattermost request")
+	}
+	if subtle.ConstantTimeCompare([]byte(form.Get("token")), []byte(token)) != 1 {
+		return errors.New("Mattermost token does not match")
+	}
+	return nil
+}
+
+// query returns the text of the slash command
+func (mattermost) query(body []byte) (string, error) {
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return "", err
+	}
+	return form.Get("text"), nil
+}
+
+// render returns a message with an attachment of the post image, or an
+// ephemeral message if there is no post
+func (mattermost) render(reply chatReply) interface{} {
+	if !reply.Found() {
+		return mattermostMessage{ResponseType: "ephemeral", Text: reply.NotFoundText()}
+	}
+	return mattermostMessage{
+		ResponseType: "in_channel",
+		Attachments: []mattermostAttachment{{
+			Fallback:  reply.Title(),
+			Title:     reply.Title(),
+			TitleLink: reply.PostURL(),
+			ImageURL:  reply.ImageURL(),
+		}},
+	}
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mattermostRequest(t *testing.T, body string) *http.Request {
+	request, err := http.NewRequest("POST", "/integrations/mattermost", strings.NewReader(body))
+	assert.NoError(t, err)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return request
+}
+
+func setMattermostToken() func() {
+	origToken := os.Getenv("MATTERMOST_TOKEN")
+	os.Setenv("MATTERMOST_TOKEN", "TOKEN")
+	return func() { os.Setenv("MATTERMOST_TOKEN", origToken) }
+}
+
+func TestMattermostVerify(t *testing.T) {
+	defer setMattermostToken()()
+	platform := mattermost{}
+	assert.NoError(t, platform.verify(nil, []byte("token=TOKEN")))
+	assert.Error(t, platform.verify(nil, []byte("token=asdf")))
+	assert.Error(t, platform.verify(nil, []byte("text=outage")))
+	os.Setenv("MATTERMOST_TOKEN", "")
+	assert.Error(t, platform.verify(nil, []byte("token=")))
+}
+
+func (s *HandlerTestSuite) TestMattermostHandler() {
+	s.addPosts(chatTestPosts)
+	defer setMattermostToken()()
+	body, err := ioutil.ReadFile("testdata/mattermost_command.txt")
+	assert.NoError(s.T(), err)
+
+	response := httptest.NewRecorder()
+	chatHandler(mattermost{})(response, mattermostRequest(s.T(), strings.TrimSpace(string(body))), s.deps)
+	assert.Equal(s.T(), response.Code, 200)
+	message := mattermostMessage{}
+	assert.NoError(s.T(), json.Unmarshal(response.Body.Bytes(), &message))
+	assert.Equal(s.T(), message.ResponseType, "in_channel")
+	attachment := message.Attachments[0]
+	assert.Equal(s.T(), attachment.Title, "Outage one")
+	assert.Equal(s.T(), attachment.TitleLink, os.Getenv("HOST")+"/post/1/outage-one")
+	assert.Equal(s.T(), attachment.ImageURL, "https://example.com/1.gif")
+}
+
+func (s *HandlerTestSuite) TestMattermostHandlerNoResults() {
+	s.addPosts(chatTestPosts)
+	defer setMattermostToken()()
+
+	response := httptest.NewRecorder()
+	chatHandler(mattermost{})(response, mattermostRequest(s.T(), "token=TOKEN&text=asdf"), s.deps)
+	message := mattermostMessage{}
+	assert.NoError(s.T(), json.Unmarshal(response.Body.Bytes(), &message))
+	assert.Equal(s.T(), message.ResponseType, "ephemeral")
+	assert.Contains(s.T(), message.Text, "No reactions found")
+}
+
+func (s *HandlerTestSuite) TestMattermostHandlerUnauthorized() {
+	s.addPosts(chatTestPosts)
+	defer setMattermostToken()()
+
+	response := httptest.NewRecorder()
+	chatHandler(mattermost{})(response, mattermostRequest(s.T(), "token=asdf&text=outage"), s.deps)
+	assert.Equal(s.T(), response.Code, 401)
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/albertyw/reaction-pics/tumblr"
+)
+
+const siteName = "Reaction Pics"
+
+// metaHeader is a meta tag in the head of the index page, identified by
+// either a name or a property attribute
+type metaHeader struct {
+	Name     string
+	Property string
+	Content  string
+}
+
+// linkHeader is a link tag in the head of the index page
+type linkHeader struct {
+	Rel   string
+	Type  string
+	Href  string
+	Title string
+}
+
+// pageMetadata is the metadata rendered in the head of the index page
+type pageMetadata struct {
+	MetaHeaders    []metaHeader
+	LinkHeaders    []linkHeader
+	StructuredData interface{}
+}
+
+// imageObject is schema.org ImageObject structured data
+type imageObject struct {
+	Context        string `json:"@context"`
+	Type           string `json:"@type"`
+	Name           string `json:"name"`
+	URL            string `json:"url"`
+	ContentURL     string `json:"contentUrl"`
+	Width          int    `json:"width,omitempty"`
+	Height         int    `json:"height,omitempty"`
+	EncodingFormat string `json:"encodingFormat,omitempty"`
+}
+
+// postMetadata returns the social, discovery, and structured metadata for
+// a post page
+func postMetadata(post tumblr.Post) pageMetadata {
+	postURL := os.Getenv("HOST") + post.InternalURL()
+	headers := []metaHeader{
+		{Property: "og:title", Content: post.Title},
+		{Property: "og:type", Content: "website"},
+		{Property: "og:url", Content: postURL},
+		{Property: "og:site_name", Content: siteName},
+		{Property: "og:image", Content: post.Image},
+	}
+	data := imageObject{
+		Context:    "https://schema.org",
+		Type:       "ImageObject",
+		Name:       post.Title,
+		URL:        postURL,
+		ContentURL: post.Image,
+	}
+	if post.Meta != nil {
+		headers = append(headers,
+			metaHeader{Property: "og:image:width", Content: strconv.Itoa(post.Meta.Width)},
+			metaHeader{Property: "og:image:height", Content: strconv.Itoa(post.Meta.Height)},
+			metaHeader{Property: "og:image:type", Content: post.Meta.MimeType},
+		)
+		data.Width = post.Meta.Width
+		data.Height = post.Meta.Height
+		data.EncodingFormat = post.Meta.MimeType
+	}
+	headers = append(headers,
+		metaHeader{Name: "twitter:card", Content: "summary_large_image"},
+		metaHeader{Name: "twitter:title", Content: post.Title},
+		metaHeader{Name: "twitter:image", Content: post.Image},
+		metaHeader{Name: "twitter:image:alt", Content: post.Title},
+	)
+	links := []linkHeader{{Rel: "canonical", Href: postURL}}
+	links = append(links, oembedLinks(post)...)
+	return pageMetadata{
+		MetaHeaders:    headers,
+		LinkHeaders:    links,
+		StructuredData: data,
+	}
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"os"
+	"testing"
+
+	"github.com/albertyw/reaction-pics/tumblr"
+	"github.com/stretchr/testify/assert"
+)
+
+func findMetaHeader(headers []metaHeader, key string) *metaHeader {
+	for _, header := range headers {
+		if header.Name == key || header.Property == key {
+			return &header
+		}
+	}
+	return nil
+}
+
+func TestPostMetadata(t *testing.T) {
+	post := tumblr.Post{
+		ID:    1234,
+		Title: "Post Title",
+		Image: "https://img.reaction.pics/file/reaction-pics/abcd.gif",
+		Meta:  &tumblr.ImageMeta{Width: 500, Height: 280, MimeType: "image/gif"},
+	}
+	postURL := os.Getenv("HOST") + "/post/1234/post-title"
+	metadata := postMetadata(post)
+
+	expected := map[string]string{
+		"og:title":          "Post Title",
+		"og:type":           "website",
+		"og:url":            postURL,
+		"og:site_name":      siteName,
+		"og:image":          post.Image,
+		"og:image:width":    "500",
+		"og:image:height":   "280",
+		"og:image:type":     "image/gif",
+		"twitter:card":      "summary_large_image",
+		"twitter:title":     "Post Title",
+		"twitter:image":     post.Image,
+		"twitter:image:alt": "Post Title",
+	}
+	for key, content := range expected {
+		header := findMetaHeader(metadata.MetaHeaders, key)
+		assert.NotNil(t, header, key)
+		assert.Equal(t, header.Content, content, key)
+	}
+	assert.Equal(t, findMetaHeader(metadata.MetaHeaders, "twitter:card").Property, "")
+	assert.Equal(t, findMetaHeader(metadata.MetaHeaders, "og:url").Name, "")
+
+	assert.Equal(t, metadata.LinkHeaders[0], linkHeader{Rel: "canonical", Href: postURL})
+	assert.Equal(t, len(metadata.LinkHeaders), 3)
+
+	data := metadata.StructuredData.(imageObject)
+	assert.Equal(t, data.Type, "ImageObject")
+	assert.Equal(t, data.ContentURL, post.Image)
+	assert.Equal(t, data.URL, postURL)
+	assert.Equal(t, data.Width, 500)
+	assert.Equal(t, data.EncodingFormat, "image/gif")
+}
+
+func TestPostMetadataUnindexed(t *testing.T) {
+	metadata := postMetadata(tumblr.Post{ID: 1234, Title: "Post Title"})
+	assert.Nil(t, findMetaHeader(metadata.MetaHeaders, "og:image:width"))
+	assert.Equal(t, metadata.StructuredData.(imageObject).Width, 0)
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/albertyw/reaction-pics/tumblr"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+const (
+	metricsNamespace = "reactionpics"
+	metricsPath      = "/metrics"
+)
+
+// metricsMethods are the request methods recorded in request metrics, and
+// every other method is recorded as "other"
+var metricsMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodOptions: true,
+}
+
+// metrics are the Prometheus metrics of the server
+type metrics struct {
+	registry      *prometheus.Registry
+	requests      *prometheus.CounterVec
+	latency       *prometheus.HistogramVec
+	searchResults prometheus.Histogram
+}
+
+// newMetrics returns metrics for the routes, board, and search cache of a
+// server, along with Go runtime and process metrics
+func newMetrics(board *tumblr.Board, searches *searchCache) *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_requests_total",
+			Help:      "Number of http requests by route, method, and status code.",
+		}, []string{"route", "method", "code"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Time taken to respond to http requests by route.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route"}),
+		searchResults: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "search_results",
+			Help:      "Number of posts matching each search.",
+			Buckets:   []float64{0, 1, 5, 10, 20, 50, 100, 250, 500, 1000},
+		}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.latency,
+		m.searchResults,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "board_posts",
+			Help:      "Number of posts on the board.",
+		}, func() float64 { return float64(board.Len()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "board_version",
+			Help:      "Number of times posts have been added to or removed from the board.",
+		}, func() float64 { return float64(board.Version()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "board_load_duration_seconds",
+			Help:      "Time taken to read saved posts into the board, or 0 while loading.",
+		}, func() float64 { return board.LoadDuration().Seconds() }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace:   metricsNamespace,
+			Name:        "cache_hits_total",
+			Help:        "Number of cache lookups that found an entry.",
+			ConstLabels: prometheus.Labels{"cache": "search"},
+		}, func() float64 { return float64(searches.stats().Hits) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace:   metricsNamespace,
+			Name:        "cache_misses_total",
+			Help:        "Number of cache lookups that did not find an entry.",
+			ConstLabels: prometheus.Labels{"cache": "search"},
+		}, func() float64 { return float64(searches.stats().Misses) }),
+	)
+	return m
+}
+
+// observeRequest records the status code and latency of a request to a route
+func (m *metrics) observeRequest(route, method string, status int, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	if !metricsMethods[method] {
+		method = "other"
+	}
+	m.requests.WithLabelValues(route, method, strconv.Itoa(status)).Inc()
+	m.latency.WithLabelValues(route).Observe(duration.Seconds())
+}
+
+// observeSearch records the number of posts matching a search
+func (m *metrics) observeSearch(total int) {
+	if m == nil {
+		return
+	}
+	m.searchResults.Observe(float64(total))
+}
+
+// handler returns an http handler that writes the metrics in the Prometheus
+// exposition format
+func (m *metrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// metricsHandler returns the metrics on the main server
+func metricsHandler(w http.ResponseWriter, r *http.Request, d handlerDeps) {
+	d.metrics.handler().ServeHTTP(w, r)
+}
+
+// serveMetrics serves the metrics on METRICS_PORT if it is set, so that they
+// can be kept off the public port, and returns whether it did.  It returns an
+// error if the port cannot be listened on.
+func serveMetrics(m *metrics, logger *zap.SugaredLogger) (bool, error) {
+	port := os.Getenv("METRICS_PORT")
+	if port == "" {
+		return false, nil
+	}
+	address := ":" + port
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return true, errors.Wrap(err, "Cannot listen for metrics")
+	}
+	mux := http.NewServeMux()
+	mux.Handle(metricsPath, m.handler())
+	logger.Infof("metrics listening on %s", address)
+	go func() {
+		err := http.Serve(listener, mux)
+		logger.Error(err)
+	}()
+	return true, nil
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/albertyw/reaction-pics/tumblr"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func scrapeMetrics(t *testing.T, d handlerDeps) string {
+	request := httptest.NewRequest("GET", metricsPath, nil)
+	response := httptest.NewRecorder()
+	metricsHandler(response, request, d)
+	assert.Equal(t, http.StatusOK, response.Code)
+	return response.Body.String()
+}
+
+func (s *HandlerTestSuite) TestMetrics() {
+	s.addPosts(chatTestPosts)
+	s.deps.searches = newSearchCache(10)
+	s.deps.metrics = newMetrics(s.deps.board, s.deps.searches)
+	generator := handlerGenerator{logger: zap.NewNop().Sugar(), deps: s.deps}
+	_, handler := generator.newHandler("/search", searchHandler)
+	for i := 0; i < 2; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/search?query=outage", nil))
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PATCH", "/search?query=deploy", nil))
+
+	body := scrapeMetrics(s.T(), s.deps)
+	assert.Contains(s.T(), body, `reactionpics_http_requests_total{code="200",method="GET",route="/search"} 2`)
+	assert.Contains(s.T(), body, `reactionpics_http_requests_total{code="200",method="other",route="/search"} 1`)
+	assert.Contains(s.T(), body, `reactionpics_http_request_duration_seconds_count{route="/search"} 3`)
+	assert.Contains(s.T(), body, `reactionpics_search_results_bucket{le="1"} 1`)
+	assert.Contains(s.T(), body, `reactionpics_search_results_count 3`)
+	assert.Contains(s.T(), body, `reactionpics_board_posts 3`)
+	assert.Contains(s.T(), body, `reactionpics_board_version 3`)
+	assert.Contains(s.T(), body, `reactionpics_board_load_duration_seconds 0`)
+	assert.Contains(s.T(), body, `reactionpics_cache_hits_total{cache="search"} 1`)
+	assert.Contains(s.T(), body, `reactionpics_cache_misses_total{cache="search"} 2`)
+	assert.Contains(s.T(), body, `go_goroutines`)
+}
+
+func TestMetricsNil(t *testing.T) {
+	var m *metrics
+	m.observeRequest("/", http.MethodGet, http.StatusOK, time.Second)
+	m.observeSearch(1)
+}
+
+func TestServeMetrics(t *testing.T) {
+	defer os.Setenv("METRICS_PORT", os.Getenv("METRICS_PORT"))
+	board := tumblr.NewBoard([]tumblr.Post{})
+	m := newMetrics(&board, nil)
+	logger := zap.NewNop().Sugar()
+
+	os.Setenv("METRICS_PORT", "")
+	serving, err := serveMetrics(m, logger)
+	assert.NoError(t, err)
+	assert.False(t, serving)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+	os.Setenv("METRICS_PORT", strconv.Itoa(port))
+	serving, err = serveMetrics(m, logger)
+	assert.NoError(t, err)
+	assert.True(t, serving)
+
+	_, err = serveMetrics(m, logger)
+	assert.Error(t, err)
+
+	var response *http.Response
+	for i := 0; i < 50; i++ {
+		response, err = http.Get("http://127.0.0.1:" + strconv.Itoa(port) + metricsPath)
+		if err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.NoError(t, err)
+	defer response.Body.Close()
+	body, err := ioutil.ReadAll(response.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), "reactionpics_board_posts 0")
+}
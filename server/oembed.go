@@ -0,0 +1,137 @@
+package server
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/albertyw/reaction-pics/tumblr"
+	"github.com/pkg/errors"
+	"github.com/rollbar/rollbar-go"
+)
+
+const (
+	// oembedDefaultSize is the width and height reported for images that
+	// have not been indexed
+	oembedDefaultSize = 500
+)
+
+// oembedResponse is a photo type oEmbed response
+type oembedResponse struct {
+	XMLName      xml.Name `json:"-" xml:"oembed"`
+	Version      string   `json:"version" xml:"version"`
+	Type         string   `json:"type" xml:"type"`
+	Title        string   `json:"title" xml:"title"`
+	URL          string   `json:"url" xml:"url"`
+	Width        int      `json:"width" xml:"width"`
+	Height       int      `json:"height" xml:"height"`
+	ProviderName string   `json:"provider_name" xml:"provider_name"`
+	ProviderURL  string   `json:"provider_url" xml:"provider_url"`
+	CacheAge     int      `json:"cache_age" xml:"cache_age"`
+}
+
+// oembedPostID returns the id of the post that a post page url links to
+func oembedPostID(postURL string) (int64, error) {
+	parsed, err := url.Parse(postURL)
+	if err != nil {
+		return 0, errors.Wrap(err, "Cannot parse url")
+	}
+	pathStrings := strings.Split(parsed.Path, "/")
+	if len(pathStrings) < 3 || pathStrings[1] != "post" {
+		return 0, errors.Errorf("Not a post url: %s", postURL)
+	}
+	return strconv.ParseInt(pathStrings[2], 10, 64)
+}
+
+// oembedSize returns the size of an image scaled down to fit within
+// maxWidth and maxHeight if they are set
+func oembedSize(meta *tumblr.ImageMeta, maxWidth, maxHeight int) (int, int) {
+	width, height := oembedDefaultSize, oembedDefaultSize
+	if meta != nil && meta.Width > 0 && meta.Height > 0 {
+		width, height = meta.Width, meta.Height
+	}
+	if maxWidth > 0 && width > maxWidth {
+		height = height * maxWidth / width
+		width = maxWidth
+	}
+	if maxHeight > 0 && height > maxHeight {
+		width = width * maxHeight / height
+		height = maxHeight
+	}
+	return width, height
+}
+
+// newOembedResponse returns the oEmbed data for a post
+func newOembedResponse(post tumblr.Post, maxWidth, maxHeight int) oembedResponse {
+	width, height := oembedSize(post.Meta, maxWidth, maxHeight)
+	return oembedResponse{
+		Version:      "1.0",
+		Type:         "photo",
+		Title:        post.Title,
+		URL:          post.Image,
+		Width:        width,
+		Height:       height,
+		ProviderName: siteName,
+		ProviderURL:  os.Getenv("HOST"),
+		CacheAge:     86400,
+	}
+}
+
+// oembedLinks returns the oEmbed discovery links for a post page
+func oembedLinks(post tumblr.Post) []linkHeader {
+	params := url.Values{}
+	params.Set("url", os.Getenv("HOST")+post.InternalURL())
+	links := []linkHeader{}
+	for _, format := range []string{"json", "xml"} {
+		params.Set("format", format)
+		links = append(links, linkHeader{
+			Rel:   "alternate",
+			Type:  fmt.Sprintf("application/%s+oembed", format),
+			Href:  os.Getenv("HOST") + "/oembed?" + params.Encode(),
+			Title: post.Title,
+		})
+	}
+	return links
+}
+
+// oembedHandler is an http handler that returns oEmbed data for a post page
+// url in json or xml format
+func oembedHandler(w http.ResponseWriter, r *http.Request, d handlerDeps) {
+	params := r.URL.Query()
+	postID, err := oembedPostID(params.Get("url"))
+	if err != nil {
+		d.logger.Warn(err)
+		rollbarRequestError(rollbar.WARN, r, err)
+		http.NotFound(w, r)
+		return
+	}
+	post := d.board.GetPostByID(postID)
+	if post == nil {
+		err = errors.New("Cannot find post")
+		d.logger.Warn(err)
+		rollbarRequestError(rollbar.WARN, r, err)
+		http.NotFound(w, r)
+		return
+	}
+	maxWidth, _ := strconv.Atoi(params.Get("maxwidth"))
+	maxHeight, _ := strconv.Atoi(params.Get("maxheight"))
+	response := newOembedResponse(*post, maxWidth, maxHeight)
+	switch params.Get("format") {
+	case "", "json":
+		data, _ := json.Marshal(response)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	case "xml":
+		data, _ := xml.Marshal(response)
+		w.Header().Set("Content-Type", "text/xml")
+		fmt.Fprint(w, xml.Header)
+		w.Write(data)
+	default:
+		http.Error(w, "Unsupported format", http.StatusNotImplemented)
+	}
+}
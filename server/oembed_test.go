@@ -0,0 +1,116 @@
+package server
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/albertyw/reaction-pics/tumblr"
+	"github.com/stretchr/testify/assert"
+)
+
+var oembedTestPosts = []tumblr.Post{{
+	ID:    1234,
+	Title: "Post Title",
+	Image: "https://img.reaction.pics/file/reaction-pics/abcd.gif",
+	Meta:  &tumblr.ImageMeta{Width: 500, Height: 280},
+}}
+
+func oembedRequest(t *testing.T, postURL, format string) *http.Request {
+	params := url.Values{}
+	params.Set("url", postURL)
+	params.Set("format", format)
+	request, err := http.NewRequest("GET", "/oembed?"+params.Encode(), nil)
+	assert.NoError(t, err)
+	return request
+}
+
+func TestOembedPostID(t *testing.T) {
+	postID, err := oembedPostID("https://www.reaction.pics/post/123/title")
+	assert.NoError(t, err)
+	assert.Equal(t, postID, int64(123))
+	postID, err = oembedPostID("/post/123")
+	assert.NoError(t, err)
+	assert.Equal(t, postID, int64(123))
+	_, err = oembedPostID("https://www.reaction.pics/static/123")
+	assert.Error(t, err)
+	_, err = oembedPostID("https://www.reaction.pics/post/asdf")
+	assert.Error(t, err)
+}
+
+func TestOembedSize(t *testing.T) {
+	meta := &tumblr.ImageMeta{Width: 500, Height: 280}
+	width, height := oembedSize(meta, 0, 0)
+	assert.Equal(t, []int{width, height}, []int{500, 280})
+	width, height = oembedSize(meta, 250, 0)
+	assert.Equal(t, []int{width, height}, []int{250, 140})
+	width, height = oembedSize(meta, 0, 140)
+	assert.Equal(t, []int{width, height}, []int{250, 140})
+	width, height = oembedSize(nil, 0, 0)
+	assert.Equal(t, []int{width, height}, []int{oembedDefaultSize, oembedDefaultSize})
+}
+
+func (s *HandlerTestSuite) TestOembedHandlerJSON() {
+	s.addPosts(oembedTestPosts)
+	response := httptest.NewRecorder()
+	request := oembedRequest(s.T(), "https://www.reaction.pics/post/1234/post-title", "json")
+	oembedHandler(response, request, s.deps)
+	assert.Equal(s.T(), response.Code, 200)
+	assert.Equal(s.T(), response.Header().Get("Content-Type"), "application/json")
+	data := oembedResponse{}
+	assert.NoError(s.T(), json.Unmarshal(response.Body.Bytes(), &data))
+	assert.Equal(s.T(), data.Type, "photo")
+	assert.Equal(s.T(), data.Version, "1.0")
+	assert.Equal(s.T(), data.Title, "Post Title")
+	assert.Equal(s.T(), data.URL, "https://img.reaction.pics/file/reaction-pics/abcd.gif")
+	assert.Equal(s.T(), data.Width, 500)
+	assert.Equal(s.T(), data.Height, 280)
+	assert.Equal(s.T(), data.ProviderURL, os.Getenv("HOST"))
+}
+
+func (s *HandlerTestSuite) TestOembedHandlerXML() {
+	s.addPosts(oembedTestPosts)
+	response := httptest.NewRecorder()
+	request := oembedRequest(s.T(), "https://www.reaction.pics/post/1234/post-title", "xml")
+	oembedHandler(response, request, s.deps)
+	assert.Equal(s.T(), response.Code, 200)
+	assert.Equal(s.T(), response.Header().Get("Content-Type"), "text/xml")
+	data := oembedResponse{}
+	assert.NoError(s.T(), xml.Unmarshal(response.Body.Bytes(), &data))
+	assert.Equal(s.T(), data.Type, "photo")
+	assert.Equal(s.T(), data.Width, 500)
+}
+
+func (s *HandlerTestSuite) TestOembedHandlerErrors() {
+	s.addPosts(oembedTestPosts)
+	cases := []struct {
+		url    string
+		format string
+		code   int
+	}{
+		{"https://www.reaction.pics/post/1234/post-title", "yaml", 501},
+		{"https://www.reaction.pics/post/1/other", "json", 404},
+		{"https://www.reaction.pics/", "json", 404},
+	}
+	for _, c := range cases {
+		response := httptest.NewRecorder()
+		oembedHandler(response, oembedRequest(s.T(), c.url, c.format), s.deps)
+		assert.Equal(s.T(), response.Code, c.code, c.url)
+	}
+}
+
+func TestOembedLinks(t *testing.T) {
+	post := tumblr.Post{ID: 1234, Title: "Post Title"}
+	links := oembedLinks(post)
+	assert.Equal(t, len(links), 2)
+	assert.Equal(t, links[0].Type, "application/json+oembed")
+	assert.Equal(t, links[1].Type, "application/xml+oembed")
+	parsed, err := url.Parse(links[0].Href)
+	assert.NoError(t, err)
+	assert.Equal(t, parsed.Path, "/oembed")
+	assert.Equal(t, parsed.Query().Get("url"), os.Getenv("HOST")+"/post/1234/post-title")
+}
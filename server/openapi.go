@@ -0,0 +1,135 @@
+package server
+
+import (
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// openAPISchemas generates OpenAPI schemas for go types from their json
+// struct tags, so the document stays in sync with the api response types
+type openAPISchemas map[string]interface{}
+
+// ref returns a schema for a type, adding struct types to the components
+func (s openAPISchemas) ref(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return s.ref(t.Elem())
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": s.ref(t.Elem())}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int32:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Struct:
+		name := openAPIName(t)
+		if _, ok := s[name]; !ok {
+			s[name] = nil
+			s[name] = s.object(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]interface{}{}
+}
+
+// object returns the schema of a struct type
+func (s openAPISchemas) object(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("json"), ",")
+		if tag[0] == "-" || field.PkgPath != "" {
+			continue
+		}
+		name := tag[0]
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = s.ref(field.Type)
+		if len(tag) < 2 || tag[1] != "omitempty" {
+			required = append(required, name)
+		}
+	}
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	// OpenAPI 3.0 requires the required list to have at least one item
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// openAPIName returns the schema name of a struct type
+func openAPIName(t reflect.Type) string {
+	name := strings.TrimPrefix(t.Name(), "api")
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// openAPIDocument generates the OpenAPI 3 document of the api routes
+func openAPIDocument(routes []apiRoute) map[string]interface{} {
+	schemas := openAPISchemas{}
+	errorResponse := map[string]interface{}{
+		"description": "Error",
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{
+				"schema": schemas.ref(reflect.TypeOf(apiErrorResponse{})),
+			},
+		},
+	}
+	paths := map[string]interface{}{}
+	for _, route := range routes {
+		parameters := []interface{}{}
+		for _, parameter := range route.parameters {
+			parameters = append(parameters, map[string]interface{}{
+				"name":        parameter.Name,
+				"in":          parameter.In,
+				"required":    parameter.In == "path",
+				"description": parameter.Description,
+				"schema":      map[string]interface{}{"type": parameter.Type},
+			})
+		}
+		paths[route.path] = map[string]interface{}{
+			"get": map[string]interface{}{
+				"operationId": route.operationID,
+				"summary":     route.summary,
+				"parameters":  parameters,
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "OK",
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": schemas.ref(reflect.TypeOf(route.response)),
+							},
+						},
+					},
+					"default": errorResponse,
+				},
+			},
+		}
+	}
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   siteName + " API",
+			"version": "1.0.0",
+		},
+		"servers":    []interface{}{map[string]interface{}{"url": os.Getenv("HOST")}},
+		"paths":      paths,
+		"components": map[string]interface{}{"schemas": map[string]interface{}(schemas)},
+	}
+}
+
+// openAPIHandler returns the OpenAPI document of the api
+func openAPIHandler(w http.ResponseWriter, r *http.Request, d handlerDeps) {
+	writeAPIJSON(w, r, d, http.StatusOK, openAPIDocument(apiRoutes()))
+}
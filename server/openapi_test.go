@@ -0,0 +1,61 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestOpenAPIDocument(t *testing.T) {
+	data, err := json.Marshal(openAPIDocument(apiRoutes()))
+	assert.NoError(t, err)
+	document := struct {
+		OpenAPI    string                            `json:"openapi"`
+		Paths      map[string]map[string]interface{} `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]map[string]interface{} `json:"properties"`
+				Required   []string                          `json:"required"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}{}
+	assert.NoError(t, json.Unmarshal(data, &document))
+	assert.Equal(t, document.OpenAPI, "3.0.3")
+	for _, route := range apiRoutes() {
+		assert.Contains(t, document.Paths, route.path)
+	}
+
+	post := document.Components.Schemas["Post"]
+	assert.Equal(t, post.Properties["id"]["type"], "integer")
+	assert.Equal(t, post.Properties["meta"]["$ref"], "#/components/schemas/ImageMeta")
+	assert.Contains(t, post.Required, "permalink")
+	assert.NotContains(t, post.Required, "tags")
+
+	search := document.Components.Schemas["SearchResponse"]
+	assert.Equal(t, search.Properties["posts"]["type"], "array")
+	assert.Contains(t, document.Components.Schemas, "ErrorResponse")
+	assert.Contains(t, document.Components.Schemas, "ErrorBody")
+}
+
+func TestOpenAPIObjectOptional(t *testing.T) {
+	type optional struct {
+		Name string `json:"name,omitempty"`
+	}
+	schema := openAPISchemas{}.object(reflect.TypeOf(optional{}))
+	assert.Contains(t, schema, "properties")
+	assert.NotContains(t, schema, "required")
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	request, err := http.NewRequest("GET", "/api/v1/openapi.json", nil)
+	assert.NoError(t, err)
+	response := httptest.NewRecorder()
+	openAPIHandler(response, request, handlerDeps{logger: zap.NewNop().Sugar()})
+	assert.Equal(t, response.Code, 200)
+	assert.Contains(t, response.Body.String(), `"/api/v1/posts/{id}"`)
+}
@@ -0,0 +1,221 @@
+package server
+
+import (
+	"crypto/sha256"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+)
+
+// rateLimitSweepInterval is how often buckets that have refilled are removed
+const rateLimitSweepInterval = time.Minute
+
+// rateLimit is a token bucket size and refill rate
+type rateLimit struct {
+	// Rate is the number of requests per second, or 0 for no limit
+	Rate  float64 `yaml:"rate"`
+	Burst int     `yaml:"burst"`
+}
+
+// rateLimitToken is the limit of clients sending an API token
+type rateLimitToken struct {
+	Name      string `yaml:"name"`
+	Token     string `yaml:"token"`
+	rateLimit `yaml:",inline"`
+}
+
+// rateLimitConfig is the rate limit policy of each route and API token
+type rateLimitConfig struct {
+	TrustedProxies []string             `yaml:"trustedProxies"`
+	Default        rateLimit            `yaml:"default"`
+	Routes         map[string]rateLimit `yaml:"routes"`
+	Tokens         []rateLimitToken     `yaml:"tokens"`
+}
+
+// rateLimitKey identifies a bucket.  Clients get a bucket per route, and each
+// API token has one bucket for every route, which has no route.
+type rateLimitKey struct {
+	route  string
+	client string
+}
+
+// rateLimiter keeps a token bucket for each client of each rate limited route
+// and for each API token
+type rateLimiter struct {
+	mut       *sync.Mutex
+	config    rateLimitConfig
+	proxies   []*net.IPNet
+	tokens    map[[sha256.Size]byte]rateLimitToken
+	buckets   map[rateLimitKey]*rate.Limiter
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// newRateLimiter returns a rate limiter for a config
+func newRateLimiter(config rateLimitConfig) (*rateLimiter, error) {
+	limiter := &rateLimiter{
+		mut:       &sync.Mutex{},
+		config:    config,
+		tokens:    map[[sha256.Size]byte]rateLimitToken{},
+		buckets:   map[rateLimitKey]*rate.Limiter{},
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+	for _, proxy := range config.TrustedProxies {
+		if !strings.Contains(proxy, "/") {
+			if strings.Contains(proxy, ":") {
+				proxy += "/128"
+			} else {
+				proxy += "/32"
+			}
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, errors.Wrap(err, "Cannot parse trusted proxy")
+		}
+		limiter.proxies = append(limiter.proxies, network)
+	}
+	for _, token := range config.Tokens {
+		if token.Token == "" {
+			return nil, errors.Errorf("Rate limit token %s is empty", token.Name)
+		}
+		limiter.tokens[sha256.Sum256([]byte(token.Token))] = token
+	}
+	return limiter, nil
+}
+
+// loadRateLimiter returns a rate limiter from the rate limit config
+func loadRateLimiter() (*rateLimiter, error) {
+	config := rateLimitConfig{}
+	err := loadConfig("RATE_LIMIT_CONFIG", "ratelimit.yml", &config)
+	if err != nil {
+		return nil, err
+	}
+	return newRateLimiter(config)
+}
+
+// trusted returns whether an address is one of the trusted proxies
+func (l *rateLimiter) trusted(ip net.IP) bool {
+	if l == nil {
+		return false
+	}
+	for _, proxy := range l.proxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the address of the client of a request. If the request
+// came from a trusted proxy, this is the last X-Forwarded-For address that
+// was not added by a trusted proxy. A nil limiter trusts no proxies.
+func (l *rateLimiter) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !l.trusted(ip) {
+		return host
+	}
+	forwarded := []string{}
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		forwarded = append(forwarded, strings.Split(header, ",")...)
+	}
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		forwardedIP := net.ParseIP(strings.TrimSpace(forwarded[i]))
+		if forwardedIP == nil {
+			break
+		}
+		ip = forwardedIP
+		if !l.trusted(ip) {
+			break
+		}
+	}
+	return ip.String()
+}
+
+// limit returns the bucket key and limit of a request to a route
+func (l *rateLimiter) limit(r *http.Request, route string) (rateLimitKey, rateLimit) {
+	auth := r.Header.Get("Authorization")
+	if strings.HasPrefix(auth, "Bearer ") {
+		token, ok := l.tokens[sha256.Sum256([]byte(strings.TrimPrefix(auth, "Bearer ")))]
+		if ok {
+			return rateLimitKey{client: "token:" + token.Name}, token.rateLimit
+		}
+	}
+	limit, ok := l.config.Routes[route]
+	if !ok {
+		limit = l.config.Default
+	}
+	return rateLimitKey{route: route, client: "ip:" + l.clientIP(r)}, limit
+}
+
+// allow takes a token from the bucket of the client of a request to a route,
+// and returns false and how long to wait if the bucket is empty
+func (l *rateLimiter) allow(r *http.Request, route string) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+	key, limit := l.limit(r, route)
+	if limit.Rate <= 0 {
+		return true, 0
+	}
+	now := l.now()
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	l.sweep(now)
+	bucket, ok := l.buckets[key]
+	if !ok {
+		burst := limit.Burst
+		if burst < 1 {
+			burst = int(math.Max(1, math.Ceil(limit.Rate)))
+		}
+		bucket = rate.NewLimiter(rate.Limit(limit.Rate), burst)
+		l.buckets[key] = bucket
+	}
+	reservation := bucket.ReserveN(now, 1)
+	delay := reservation.DelayFrom(now)
+	if delay > 0 {
+		reservation.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// sweep removes buckets that have refilled, which behave the same as new
+// buckets, and must be called with the lock held
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, bucket := range l.buckets {
+		if bucket.TokensAt(now) >= float64(bucket.Burst()) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// rateLimitedHandler writes a 429 response asking the client to retry after
+// a delay
+func rateLimitedHandler(w http.ResponseWriter, r *http.Request, d handlerDeps, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	if strings.HasPrefix(r.URL.Path, apiPrefix+"/") {
+		writeAPIError(w, r, d, http.StatusTooManyRequests, "rate_limited", "Too many requests")
+		return
+	}
+	http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+}
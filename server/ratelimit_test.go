@@ -0,0 +1,158 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/albertyw/reaction-pics/tumblr"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func rateLimiterTest(t *testing.T) (*rateLimiter, *time.Time) {
+	config := rateLimitConfig{}
+	err := readConfig("testdata/ratelimit.yml", &config)
+	assert.NoError(t, err)
+	limiter, err := newRateLimiter(config)
+	assert.NoError(t, err)
+	now := time.Unix(1600000000, 0)
+	limiter.now = func() time.Time { return now }
+	limiter.lastSweep = now
+	return limiter, &now
+}
+
+func rateLimitRequest(remoteAddr string, headers map[string]string) *http.Request {
+	request := httptest.NewRequest("GET", "/search?query=a", nil)
+	request.RemoteAddr = remoteAddr
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+	return request
+}
+
+func TestReadRateLimitConfig(t *testing.T) {
+	config := rateLimitConfig{}
+	err := readConfig(relToAbsPath("ratelimit.yml"), &config)
+	assert.NoError(t, err)
+	assert.Equal(t, rateLimit{Rate: 5, Burst: 20}, config.Routes["/search"])
+	_, err = newRateLimiter(config)
+	assert.NoError(t, err)
+
+	_, err = newRateLimiter(rateLimitConfig{TrustedProxies: []string{"asdf"}})
+	assert.Error(t, err)
+	_, err = newRateLimiter(rateLimitConfig{Tokens: []rateLimitToken{{Name: "empty"}}})
+	assert.Error(t, err)
+}
+
+func TestLoadRateLimiter(t *testing.T) {
+	defer os.Setenv("RATE_LIMIT_CONFIG", os.Getenv("RATE_LIMIT_CONFIG"))
+	os.Setenv("RATE_LIMIT_CONFIG", "testdata/ratelimit.yml")
+	limiter, err := loadRateLimiter()
+	assert.NoError(t, err)
+	assert.NotNil(t, limiter)
+	os.Setenv("RATE_LIMIT_CONFIG", "testdata/missing.yml")
+	_, err = loadRateLimiter()
+	assert.Error(t, err)
+	os.Setenv("RATE_LIMIT_CONFIG", "testdata/ratelimit_invalid.yml")
+	_, err = loadRateLimiter()
+	assert.Error(t, err)
+}
+
+func TestRateLimiterClientIP(t *testing.T) {
+	limiter, _ := rateLimiterTest(t)
+	forwarded := map[string]string{"X-Forwarded-For": "1.1.1.1, 2.2.2.2, 10.0.0.2"}
+	assert.Equal(t, "3.3.3.3", limiter.clientIP(rateLimitRequest("3.3.3.3:1234", forwarded)))
+	assert.Equal(t, "2.2.2.2", limiter.clientIP(rateLimitRequest("127.0.0.1:1234", forwarded)))
+	assert.Equal(t, "10.0.0.1", limiter.clientIP(rateLimitRequest("10.0.0.1:1234", nil)))
+	assert.Equal(t, "10.0.0.3", limiter.clientIP(rateLimitRequest("10.0.0.1:1234", map[string]string{"X-Forwarded-For": "10.0.0.3"})))
+	assert.Equal(t, "2.2.2.2", limiter.clientIP(rateLimitRequest("10.0.0.1:1234", map[string]string{"X-Forwarded-For": "asdf, 2.2.2.2"})))
+}
+
+func TestRateLimiterAllow(t *testing.T) {
+	limiter, now := rateLimiterTest(t)
+	client := rateLimitRequest("127.0.0.1:1234", map[string]string{"X-Forwarded-For": "1.1.1.1"})
+	other := rateLimitRequest("127.0.0.1:1234", map[string]string{"X-Forwarded-For": "2.2.2.2"})
+
+	for i := 0; i < 2; i++ {
+		allowed, _ := limiter.allow(client, "/search")
+		assert.True(t, allowed)
+	}
+	allowed, retryAfter := limiter.allow(client, "/search")
+	assert.False(t, allowed)
+	assert.Equal(t, time.Second, retryAfter)
+	allowed, _ = limiter.allow(other, "/search")
+	assert.True(t, allowed)
+	allowed, _ = limiter.allow(client, "/stats.json")
+	assert.True(t, allowed)
+
+	*now = now.Add(time.Second)
+	allowed, _ = limiter.allow(client, "/search")
+	assert.True(t, allowed)
+
+	allowed, _ = limiter.allow(client, "/api/v1/search")
+	assert.True(t, allowed)
+	allowed, retryAfter = limiter.allow(client, "/api/v1/search")
+	assert.False(t, allowed)
+	assert.Equal(t, 2*time.Second, retryAfter)
+}
+
+func TestRateLimiterTokens(t *testing.T) {
+	limiter, _ := rateLimiterTest(t)
+	first := rateLimitRequest("1.1.1.1:1234", map[string]string{"Authorization": "Bearer secret-token"})
+	second := rateLimitRequest("2.2.2.2:1234", map[string]string{"Authorization": "Bearer secret-token"})
+	for i := 0; i < 5; i++ {
+		allowed, _ := limiter.allow(first, "/search")
+		assert.True(t, allowed)
+	}
+	allowed, retryAfter := limiter.allow(second, "/search")
+	assert.False(t, allowed)
+	assert.Equal(t, 100*time.Millisecond, retryAfter)
+	allowed, _ = limiter.allow(first, "/graphql")
+	assert.False(t, allowed)
+	allowed, _ = limiter.allow(rateLimitRequest("1.1.1.1:1234", nil), "/search")
+	assert.True(t, allowed)
+
+	unknown := rateLimitRequest("1.1.1.1:1234", map[string]string{"Authorization": "Bearer asdf"})
+	key, limit := limiter.limit(unknown, "/search")
+	assert.Equal(t, rateLimitKey{route: "/search", client: "ip:1.1.1.1"}, key)
+	assert.Equal(t, rateLimit{Rate: 1, Burst: 2}, limit)
+}
+
+func TestRateLimiterSweep(t *testing.T) {
+	limiter, now := rateLimiterTest(t)
+	limiter.allow(rateLimitRequest("1.1.1.1:1234", nil), "/search")
+	assert.Len(t, limiter.buckets, 1)
+	*now = now.Add(rateLimitSweepInterval)
+	limiter.allow(rateLimitRequest("2.2.2.2:1234", nil), "/search")
+	assert.Len(t, limiter.buckets, 1)
+}
+
+func TestRateLimitedHandler(t *testing.T) {
+	limiter, _ := rateLimiterTest(t)
+	board := tumblr.NewBoard([]tumblr.Post{})
+	generator := handlerGenerator{
+		logger:  zap.NewNop().Sugar(),
+		deps:    handlerDeps{logger: zap.NewNop().Sugar(), board: &board},
+		limiter: limiter,
+	}
+	_, handler := generator.newHandler("/api/v1/search", apiSearchHandler)
+	request := rateLimitRequest("1.1.1.1:1234", nil)
+	request.URL.Path = "/api/v1/search"
+
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	assert.Equal(t, http.StatusOK, response.Code)
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	assert.Equal(t, http.StatusTooManyRequests, response.Code)
+	assert.Equal(t, "2", response.Header().Get("Retry-After"))
+	assert.Contains(t, response.Body.String(), `"code":"rate_limited"`)
+
+	response = httptest.NewRecorder()
+	rateLimitedHandler(response, rateLimitRequest("1.1.1.1:1234", nil), generator.deps, 10*time.Millisecond)
+	assert.Equal(t, http.StatusTooManyRequests, response.Code)
+	assert.Equal(t, "1", response.Header().Get("Retry-After"))
+}
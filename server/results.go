@@ -0,0 +1,73 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/albertyw/reaction-pics/tumblr"
+)
+
+// resultsPage is a page of posts, which is rendered into the index page and
+// returned as json by the search and post data endpoints
+type resultsPage struct {
+	Data         []tumblr.PostJSON `json:"data"`
+	Offset       int               `json:"offset"`
+	TotalResults int               `json:"totalResults"`
+}
+
+// NextOffset returns the offset of the page after this one
+func (p resultsPage) NextOffset() int {
+	return p.Offset + len(p.Data)
+}
+
+// HasNext returns whether there are more results after this page
+func (p resultsPage) HasNext() bool {
+	return p.NextOffset() < p.TotalResults
+}
+
+// searchPosts returns a page of posts matching a query and the number of
+// matching posts, and records the number in the search metrics
+func searchPosts(d handlerDeps, query string, filter tumblr.ImageFilter, offset, limit int) ([]tumblr.Post, int) {
+	posts, total := d.searches.search(d.board, query, filter, offset, limit)
+	d.metrics.observeSearch(total)
+	return posts, total
+}
+
+// searchResults returns a page of posts matching a query
+func searchResults(d handlerDeps, query string, filter tumblr.ImageFilter, offset int) resultsPage {
+	posts, total := searchPosts(d, query, filter, offset, maxResults)
+	data := make([]tumblr.PostJSON, len(posts))
+	for i, post := range posts {
+		data[i] = post.ToJSONStruct()
+	}
+	return resultsPage{
+		Data:         data,
+		Offset:       offset,
+		TotalResults: total,
+	}
+}
+
+// postResults returns a page with a single post
+func postResults(post tumblr.Post) resultsPage {
+	return resultsPage{
+		Data:         []tumblr.PostJSON{post.ToJSONStruct()},
+		Offset:       0,
+		TotalResults: 1,
+	}
+}
+
+// searchResultsFromRequest returns the page of posts for the query, offset,
+// and image filter parameters of a request, or an error if the image filter
+// is invalid
+func searchResultsFromRequest(r *http.Request, d handlerDeps) (resultsPage, error) {
+	query := r.URL.Query().Get("query")
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	filter, err := imageFilterFromRequest(r, d.board)
+	if err != nil {
+		return resultsPage{}, err
+	}
+	return searchResults(d, query, filter, offset), nil
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/albertyw/reaction-pics/tumblr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResultsPagePagination(t *testing.T) {
+	posts := []tumblr.Post{}
+	for i := 1; i <= maxResults+5; i++ {
+		posts = append(posts, tumblr.Post{ID: int64(i), Title: "Outage"})
+	}
+	board := tumblr.NewBoard(posts)
+	d := handlerDeps{board: &board, searches: newSearchCache(10)}
+
+	page := searchResults(d, "outage", tumblr.ImageFilter{}, 0)
+	assert.Equal(t, len(page.Data), maxResults)
+	assert.Equal(t, page.TotalResults, maxResults+5)
+	assert.Equal(t, page.NextOffset(), maxResults)
+	assert.True(t, page.HasNext())
+
+	page = searchResults(d, "outage", tumblr.ImageFilter{}, page.NextOffset())
+	assert.Equal(t, len(page.Data), 5)
+	assert.False(t, page.HasNext())
+}
+
+func TestPostResults(t *testing.T) {
+	page := postResults(tumblr.Post{ID: 1, Title: "Outage"})
+	assert.Equal(t, len(page.Data), 1)
+	assert.Equal(t, page.Data[0].InternalURL, "/post/1/outage")
+	assert.Equal(t, page.TotalResults, 1)
+	assert.False(t, page.HasNext())
+}
+
+func TestSearchResultsFromRequest(t *testing.T) {
+	board := tumblr.NewBoard([]tumblr.Post{{ID: 1, Title: "Outage"}, {ID: 2, Title: "Deploy"}})
+	request, err := http.NewRequest("GET", "/?query=outage&offset=-1", nil)
+	assert.NoError(t, err)
+
+	page, err := searchResultsFromRequest(request, handlerDeps{board: &board})
+	assert.NoError(t, err)
+	assert.Equal(t, page.Offset, 0)
+	assert.Equal(t, page.TotalResults, 1)
+	assert.Equal(t, page.Data[0].Title, "Outage")
+
+	request, err = http.NewRequest("GET", "/?query=outage&type=video", nil)
+	assert.NoError(t, err)
+	_, err = searchResultsFromRequest(request, handlerDeps{board: &board})
+	assert.Error(t, err)
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/rollbar/rollbar-go"
+)
+
+// robotsBlockedPaths are disallowed for every user agent
+var robotsBlockedPaths = []string{"/search", "/admin"}
+
+// robotsGroup is a set of rules for some user agents
+type robotsGroup struct {
+	UserAgents []string `yaml:"userAgents"`
+	Allow      []string `yaml:"allow"`
+	Disallow   []string `yaml:"disallow"`
+}
+
+// robotsConfig is the crawl policy that robots.txt is generated from
+type robotsConfig struct {
+	IndexEnvironments []string      `yaml:"indexEnvironments"`
+	Groups            []robotsGroup `yaml:"groups"`
+}
+
+// indexable returns whether crawlers may index an environment
+func (c robotsConfig) indexable(environment string) bool {
+	for _, e := range c.IndexEnvironments {
+		if e == environment {
+			return true
+		}
+	}
+	return false
+}
+
+// robotsTxt generates a robots.txt for an environment, with a Sitemap line
+// for host
+func robotsTxt(config robotsConfig, environment, host string) string {
+	var b strings.Builder
+	if !config.indexable(environment) {
+		b.WriteString("User-agent: *\nDisallow: /\n")
+		return b.String()
+	}
+	groups := config.Groups
+	if len(groups) == 0 {
+		groups = []robotsGroup{{UserAgents: []string{"*"}}}
+	}
+	for i, group := range groups {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		for _, agent := range group.UserAgents {
+			fmt.Fprintf(&b, "User-agent: %s\n", agent)
+		}
+		for _, path := range group.Allow {
+			fmt.Fprintf(&b, "Allow: %s\n", path)
+		}
+		disallowed := map[string]bool{}
+		for _, path := range append(group.Disallow, robotsBlockedPaths...) {
+			if !disallowed[path] {
+				fmt.Fprintf(&b, "Disallow: %s\n", path)
+				disallowed[path] = true
+			}
+		}
+	}
+	if host != "" {
+		fmt.Fprintf(&b, "\nSitemap: %s%s\n", host, sitemapPath)
+	}
+	return b.String()
+}
+
+// robotsTxtHandler returns the robots.txt generated from the robots config
+func robotsTxtHandler(w http.ResponseWriter, r *http.Request, d handlerDeps) {
+	config := robotsConfig{}
+	err := loadConfig("ROBOTS_CONFIG", "robots.yml", &config)
+	if err != nil {
+		d.logger.Error(err)
+		rollbarRequestError(rollbar.ERR, r, err)
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, robotsTxt(config, os.Getenv("ENVIRONMENT"), os.Getenv("HOST")))
+}
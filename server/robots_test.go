@@ -0,0 +1,75 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestRobotsTxtDefault(t *testing.T) {
+	config := robotsConfig{}
+	err := readConfig(relToAbsPath("robots.yml"), &config)
+	assert.NoError(t, err)
+	expected := "User-agent: *\n" +
+		"Allow: /\n" +
+		"Disallow: /search\n" +
+		"Disallow: /admin\n" +
+		"\n" +
+		"Sitemap: https://www.reaction.pics/sitemap.xml\n"
+	assert.Equal(t, robotsTxt(config, "production", "https://www.reaction.pics"), expected)
+}
+
+func TestRobotsTxtNonProduction(t *testing.T) {
+	config := robotsConfig{}
+	err := readConfig(relToAbsPath("robots.yml"), &config)
+	assert.NoError(t, err)
+	expected := "User-agent: *\nDisallow: /\n"
+	assert.Equal(t, robotsTxt(config, "development", "https://www.reaction.pics"), expected)
+	assert.Equal(t, robotsTxt(config, "", "https://www.reaction.pics"), expected)
+}
+
+func TestRobotsTxtGroups(t *testing.T) {
+	config := robotsConfig{}
+	err := readConfig("testdata/robots.yml", &config)
+	assert.NoError(t, err)
+	expected := "User-agent: Googlebot\n" +
+		"User-agent: Bingbot\n" +
+		"Allow: /\n" +
+		"Disallow: /static/\n" +
+		"Disallow: /search\n" +
+		"Disallow: /admin\n" +
+		"\n" +
+		"User-agent: GPTBot\n" +
+		"Disallow: /\n" +
+		"Disallow: /search\n" +
+		"Disallow: /admin\n"
+	assert.Equal(t, robotsTxt(config, "staging", ""), expected)
+}
+
+func TestRobotsTxtHandler(t *testing.T) {
+	origConfig := os.Getenv("ROBOTS_CONFIG")
+	origEnvironment := os.Getenv("ENVIRONMENT")
+	defer func() {
+		os.Setenv("ROBOTS_CONFIG", origConfig)
+		os.Setenv("ENVIRONMENT", origEnvironment)
+	}()
+	os.Setenv("ROBOTS_CONFIG", "testdata/robots.yml")
+	os.Setenv("ENVIRONMENT", "staging")
+	d := handlerDeps{logger: zap.NewNop().Sugar()}
+
+	request, err := http.NewRequest("GET", "/robots.txt", nil)
+	assert.NoError(t, err)
+	response := httptest.NewRecorder()
+	robotsTxtHandler(response, request, d)
+	assert.Equal(t, response.Code, 200)
+	assert.Contains(t, response.Body.String(), "User-agent: GPTBot\n")
+
+	os.Setenv("ROBOTS_CONFIG", "testdata/asdf.yml")
+	response = httptest.NewRecorder()
+	robotsTxtHandler(response, request, d)
+	assert.Equal(t, response.Code, 500)
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"connectrpc.com/connect"
+	reactionpicsv1 "github.com/albertyw/reaction-pics/gen/reactionpics/v1"
+	"github.com/albertyw/reaction-pics/gen/reactionpics/v1/reactionpicsv1connect"
+	"github.com/albertyw/reaction-pics/tumblr"
+	"github.com/pkg/errors"
+)
+
+const rpcMaxLimit = 100
+
+// rpcImageTypes maps protobuf image types to image filter types
+var rpcImageTypes = map[reactionpicsv1.ImageType]string{
+	reactionpicsv1.ImageType_IMAGE_TYPE_UNSPECIFIED: "",
+	reactionpicsv1.ImageType_IMAGE_TYPE_STATIC:      tumblr.ImageTypeStatic,
+	reactionpicsv1.ImageType_IMAGE_TYPE_ANIMATED:    tumblr.ImageTypeAnimated,
+}
+
+// indexService serves the IndexService rpc over Connect, gRPC, and gRPC-Web
+type indexService struct {
+	deps handlerDeps
+}
+
+// newRPCPost converts a post to a protobuf post with absolute urls
+func newRPCPost(post tumblr.Post) *reactionpicsv1.Post {
+	apiPost := newAPIPost(post)
+	rpcPost := &reactionpicsv1.Post{
+		Id:        apiPost.ID,
+		Title:     apiPost.Title,
+		Url:       apiPost.URL,
+		Permalink: apiPost.Permalink,
+		Image:     apiPost.Image,
+		Likes:     apiPost.Likes,
+		Tags:      apiPost.Tags,
+		Timestamp: apiPost.Timestamp,
+	}
+	if post.Meta != nil {
+		rpcPost.Meta = &reactionpicsv1.ImageMeta{
+			Width:    int32(post.Meta.Width),
+			Height:   int32(post.Meta.Height),
+			Frames:   int32(post.Meta.Frames),
+			Duration: int32(post.Meta.Duration),
+			Size:     post.Meta.Size,
+			MimeType: post.Meta.MimeType,
+		}
+	}
+	return rpcPost
+}
+
+// Search returns a page of posts matching a query
+func (s indexService) Search(
+	ctx context.Context, req *connect.Request[reactionpicsv1.SearchRequest],
+) (*connect.Response[reactionpicsv1.SearchResponse], error) {
+	imageType, ok := rpcImageTypes[req.Msg.Type]
+	if !ok {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.Errorf("Unknown image type %d", req.Msg.Type))
+	}
+	filter := tumblr.ImageFilter{Type: imageType, MaxDuration: int(req.Msg.MaxDuration)}
+	if err := s.deps.board.ValidateImageFilter(filter); err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	offset := int(req.Msg.Offset)
+	if offset < 0 {
+		offset = 0
+	}
+	limit := int(req.Msg.Limit)
+	if limit <= 0 {
+		limit = maxResults
+	}
+	if limit > rpcMaxLimit {
+		limit = rpcMaxLimit
+	}
+	posts, total := searchPosts(s.deps, req.Msg.Query, filter, offset, limit)
+	response := &reactionpicsv1.SearchResponse{
+		Posts:        []*reactionpicsv1.Post{},
+		Offset:       int32(offset),
+		TotalResults: int32(total),
+		HasNext:      offset+len(posts) < total,
+	}
+	for _, post := range posts {
+		response.Posts = append(response.Posts, newRPCPost(post))
+	}
+	return connect.NewResponse(response), nil
+}
+
+// GetPost returns a single post by id
+func (s indexService) GetPost(
+	ctx context.Context, req *connect.Request[reactionpicsv1.GetPostRequest],
+) (*connect.Response[reactionpicsv1.GetPostResponse], error) {
+	post := s.deps.board.GetPostByID(req.Msg.Id)
+	if post == nil {
+		return nil, connect.NewError(connect.CodeNotFound, errors.New("Cannot find post"))
+	}
+	return connect.NewResponse(&reactionpicsv1.GetPostResponse{Post: newRPCPost(*post)}), nil
+}
+
+// newRPCHandler returns the path prefix and handler of the IndexService rpc
+func newRPCHandler(d handlerDeps) (string, handlerWithDeps) {
+	path, handler := reactionpicsv1connect.NewIndexServiceHandler(indexService{deps: d})
+	return path, func(w http.ResponseWriter, r *http.Request, d handlerDeps) {
+		handler.ServeHTTP(w, r)
+	}
+}
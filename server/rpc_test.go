@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"connectrpc.com/connect"
+	reactionpicsv1 "github.com/albertyw/reaction-pics/gen/reactionpics/v1"
+	"github.com/albertyw/reaction-pics/gen/reactionpics/v1/reactionpicsv1connect"
+	"github.com/albertyw/reaction-pics/tumblr"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+func rpcTestServer() *httptest.Server {
+	board := tumblr.NewBoard([]tumblr.Post{})
+	board.AddPost(tumblr.Post{ID: 1, Title: "Outage one", Image: "https://example.com/1.gif", Likes: 2, Tags: []string{"ops"}, Meta: &tumblr.ImageMeta{Frames: 10, Duration: 500, MimeType: "image/gif"}})
+	board.AddPost(tumblr.Post{ID: 2, Title: "Outage two", Image: "https://example.com/2.png", Likes: 1, Meta: &tumblr.ImageMeta{Frames: 1}})
+	board.AddPost(tumblr.Post{ID: 3, Title: "Deploy", Image: "https://example.com/3.gif", Likes: 3})
+	d := handlerDeps{logger: zap.NewNop().Sugar(), board: &board}
+	path, handler := newRPCHandler(d)
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		handler(w, r, d)
+	})
+	return httptest.NewServer(h2c.NewHandler(mux, &http2.Server{}))
+}
+
+func TestRPCSearch(t *testing.T) {
+	server := rpcTestServer()
+	defer server.Close()
+	client := reactionpicsv1connect.NewIndexServiceClient(http.DefaultClient, server.URL)
+
+	request := connect.NewRequest(&reactionpicsv1.SearchRequest{Query: "outage", Limit: 1})
+	response, err := client.Search(context.Background(), request)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), response.Msg.TotalResults)
+	assert.True(t, response.Msg.HasNext)
+	assert.Len(t, response.Msg.Posts, 1)
+	post := response.Msg.Posts[0]
+	assert.Equal(t, int64(1), post.Id)
+	assert.Equal(t, "/post/1/outage-one", post.Permalink)
+	assert.Equal(t, []string{"ops"}, post.Tags)
+	assert.Equal(t, int32(500), post.Meta.Duration)
+	assert.Equal(t, "image/gif", post.Meta.MimeType)
+
+	request = connect.NewRequest(&reactionpicsv1.SearchRequest{Query: "outage", Type: reactionpicsv1.ImageType_IMAGE_TYPE_STATIC})
+	response, err = client.Search(context.Background(), request)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), response.Msg.TotalResults)
+	assert.Equal(t, int64(2), response.Msg.Posts[0].Id)
+	assert.False(t, response.Msg.HasNext)
+
+	request = connect.NewRequest(&reactionpicsv1.SearchRequest{Type: reactionpicsv1.ImageType(9)})
+	_, err = client.Search(context.Background(), request)
+	assert.Equal(t, connect.CodeInvalidArgument, connect.CodeOf(err))
+
+	request = connect.NewRequest(&reactionpicsv1.SearchRequest{Query: "outage", MaxDuration: -1})
+	_, err = client.Search(context.Background(), request)
+	assert.Equal(t, connect.CodeInvalidArgument, connect.CodeOf(err))
+}
+
+func TestRPCGetPost(t *testing.T) {
+	server := rpcTestServer()
+	defer server.Close()
+	client := reactionpicsv1connect.NewIndexServiceClient(http.DefaultClient, server.URL)
+
+	response, err := client.GetPost(context.Background(), connect.NewRequest(&reactionpicsv1.GetPostRequest{Id: 3}))
+	assert.NoError(t, err)
+	assert.Equal(t, "Deploy", response.Msg.Post.Title)
+	assert.Nil(t, response.Msg.Post.Meta)
+
+	_, err = client.GetPost(context.Background(), connect.NewRequest(&reactionpicsv1.GetPostRequest{Id: 9}))
+	assert.Equal(t, connect.CodeNotFound, connect.CodeOf(err))
+}
+
+func TestRPCGRPCOverH2C(t *testing.T) {
+	server := rpcTestServer()
+	defer server.Close()
+	httpClient := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}}
+	client := reactionpicsv1connect.NewIndexServiceClient(httpClient, server.URL, connect.WithGRPC())
+
+	response, err := client.GetPost(context.Background(), connect.NewRequest(&reactionpicsv1.GetPostRequest{Id: 1}))
+	assert.NoError(t, err)
+	assert.Equal(t, "Outage one", response.Msg.Post.Title)
+}
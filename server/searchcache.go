@@ -0,0 +1,131 @@
+package server
+
+import (
+	"container/list"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/albertyw/reaction-pics/tumblr"
+)
+
+const defaultSearchCacheSize = 1000
+
+// Orders of cached search results
+const (
+	searchSortLikes = "likes"
+)
+
+// searchKey identifies a page of search results
+type searchKey struct {
+	query  string
+	sort   string
+	filter tumblr.ImageFilter
+	offset int
+	limit  int
+}
+
+// searchEntry is a cached page of search results
+type searchEntry struct {
+	key   searchKey
+	posts []tumblr.Post
+	total int
+}
+
+// searchCache is a least recently used cache of search results that is
+// cleared whenever the board version changes
+type searchCache struct {
+	mut      *sync.Mutex
+	capacity int
+	version  int64
+	entries  map[searchKey]*list.Element
+	order    *list.List
+	hits     int64
+	misses   int64
+}
+
+// newSearchCache returns a search cache holding up to capacity pages
+func newSearchCache(capacity int) *searchCache {
+	return &searchCache{
+		mut:      &sync.Mutex{},
+		capacity: capacity,
+		entries:  map[searchKey]*list.Element{},
+		order:    list.New(),
+	}
+}
+
+// searchCacheSize returns the number of pages to cache from
+// SEARCH_CACHE_SIZE, where 0 disables the cache
+func searchCacheSize() int {
+	size, err := strconv.Atoi(os.Getenv("SEARCH_CACHE_SIZE"))
+	if err != nil || size < 0 {
+		return defaultSearchCacheSize
+	}
+	return size
+}
+
+// get returns the cached results for a key, or runs search and caches its
+// results. Queries are lowercased because matching ignores case. The returned
+// posts are shared and must not be modified.
+func (c *searchCache) get(board *tumblr.Board, key searchKey, search func() ([]tumblr.Post, int)) ([]tumblr.Post, int) {
+	if c == nil || c.capacity == 0 {
+		return search()
+	}
+	key.query = strings.ToLower(key.query)
+	version := board.Version()
+	c.mut.Lock()
+	if version != c.version {
+		c.entries = map[searchKey]*list.Element{}
+		c.order.Init()
+		c.version = version
+	}
+	if element, ok := c.entries[key]; ok {
+		c.order.MoveToFront(element)
+		c.hits++
+		entry := element.Value.(*searchEntry)
+		c.mut.Unlock()
+		return entry.posts, entry.total
+	}
+	c.misses++
+	c.mut.Unlock()
+
+	posts, total := search()
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if _, ok := c.entries[key]; ok || c.version != version {
+		return posts, total
+	}
+	c.entries[key] = c.order.PushFront(&searchEntry{key: key, posts: posts, total: total})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*searchEntry).key)
+	}
+	return posts, total
+}
+
+// search returns a page of posts matching a query ranked by likes. Empty
+// queries return random posts, so they bypass the cache.
+func (c *searchCache) search(board *tumblr.Board, query string, filter tumblr.ImageFilter, offset, limit int) ([]tumblr.Post, int) {
+	search := func() ([]tumblr.Post, int) {
+		queriedBoard, total := board.Search(query, filter, offset, limit)
+		return queriedBoard.Posts, total
+	}
+	if query == "" {
+		return search()
+	}
+	key := searchKey{query: query, sort: searchSortLikes, filter: filter, offset: offset, limit: limit}
+	return c.get(board, key, search)
+}
+
+// stats returns the number of cache hits, misses, and cached pages
+func (c *searchCache) stats() apiCacheStats {
+	if c == nil {
+		return apiCacheStats{}
+	}
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	return apiCacheStats{Hits: c.hits, Misses: c.misses, Size: c.order.Len()}
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/albertyw/reaction-pics/tumblr"
+	"github.com/stretchr/testify/assert"
+)
+
+func (s *HandlerTestSuite) TestSearchCacheHitsAndMisses() {
+	s.addPosts(chatTestPosts)
+	cache := newSearchCache(10)
+	posts, total := cache.search(s.deps.board, "outage", tumblr.ImageFilter{}, 0, maxResults)
+	assert.Equal(s.T(), 2, total)
+	assert.Equal(s.T(), int64(1), posts[0].ID)
+	assert.Equal(s.T(), apiCacheStats{Hits: 0, Misses: 1, Size: 1}, cache.stats())
+
+	posts, total = cache.search(s.deps.board, "OUTAGE", tumblr.ImageFilter{}, 0, maxResults)
+	assert.Equal(s.T(), 2, total)
+	assert.Equal(s.T(), int64(1), posts[0].ID)
+	assert.Equal(s.T(), apiCacheStats{Hits: 1, Misses: 1, Size: 1}, cache.stats())
+
+	cache.search(s.deps.board, "outage", tumblr.ImageFilter{}, 1, maxResults)
+	cache.search(s.deps.board, "outage", tumblr.ImageFilter{Type: tumblr.ImageTypeStatic}, 0, maxResults)
+	assert.Equal(s.T(), apiCacheStats{Hits: 1, Misses: 3, Size: 3}, cache.stats())
+}
+
+func (s *HandlerTestSuite) TestSearchCacheInvalidation() {
+	s.addPosts(chatTestPosts)
+	cache := newSearchCache(10)
+	_, total := cache.search(s.deps.board, "outage", tumblr.ImageFilter{}, 0, maxResults)
+	assert.Equal(s.T(), 2, total)
+
+	s.deps.board.AddPost(tumblr.Post{ID: 4, Title: "Outage three", Likes: 10})
+	posts, total := cache.search(s.deps.board, "outage", tumblr.ImageFilter{}, 0, maxResults)
+	assert.Equal(s.T(), 3, total)
+	assert.Equal(s.T(), int64(4), posts[0].ID)
+	assert.Equal(s.T(), apiCacheStats{Hits: 0, Misses: 2, Size: 1}, cache.stats())
+}
+
+func (s *HandlerTestSuite) TestSearchCacheEviction() {
+	s.addPosts(chatTestPosts)
+	cache := newSearchCache(2)
+	cache.search(s.deps.board, "outage", tumblr.ImageFilter{}, 0, maxResults)
+	cache.search(s.deps.board, "deploy", tumblr.ImageFilter{}, 0, maxResults)
+	cache.search(s.deps.board, "outage", tumblr.ImageFilter{}, 0, maxResults)
+	cache.search(s.deps.board, "one", tumblr.ImageFilter{}, 0, maxResults)
+	assert.Equal(s.T(), apiCacheStats{Hits: 1, Misses: 3, Size: 2}, cache.stats())
+
+	// deploy was the least recently used and was evicted
+	cache.search(s.deps.board, "outage", tumblr.ImageFilter{}, 0, maxResults)
+	cache.search(s.deps.board, "deploy", tumblr.ImageFilter{}, 0, maxResults)
+	assert.Equal(s.T(), apiCacheStats{Hits: 2, Misses: 4, Size: 2}, cache.stats())
+}
+
+func (s *HandlerTestSuite) TestSearchCacheBypass() {
+	s.addPosts(chatTestPosts)
+	cache := newSearchCache(10)
+	_, total := cache.search(s.deps.board, "", tumblr.ImageFilter{}, 0, maxResults)
+	assert.Equal(s.T(), 3, total)
+	assert.Equal(s.T(), apiCacheStats{}, cache.stats())
+
+	var nilCache *searchCache
+	_, total = nilCache.search(s.deps.board, "outage", tumblr.ImageFilter{}, 0, maxResults)
+	assert.Equal(s.T(), 2, total)
+	assert.Equal(s.T(), apiCacheStats{}, nilCache.stats())
+
+	disabled := newSearchCache(0)
+	disabled.search(s.deps.board, "outage", tumblr.ImageFilter{}, 0, maxResults)
+	assert.Equal(s.T(), apiCacheStats{}, disabled.stats())
+}
+
+func TestSearchCacheSize(t *testing.T) {
+	defer os.Setenv("SEARCH_CACHE_SIZE", os.Getenv("SEARCH_CACHE_SIZE"))
+	os.Setenv("SEARCH_CACHE_SIZE", "")
+	assert.Equal(t, defaultSearchCacheSize, searchCacheSize())
+	os.Setenv("SEARCH_CACHE_SIZE", "0")
+	assert.Equal(t, 0, searchCacheSize())
+	os.Setenv("SEARCH_CACHE_SIZE", "-1")
+	assert.Equal(t, defaultSearchCacheSize, searchCacheSize())
+}
+
+func (s *HandlerTestSuite) TestSearchCacheFeedSort() {
+	s.addPosts(feedTestPosts)
+	s.deps.searches = newSearchCache(10)
+	newest := feedPosts(s.deps, "Outage", feedNewest)
+	top := feedPosts(s.deps, "outage", feedTop)
+	assert.Equal(s.T(), []int64{newest[0].ID, newest[1].ID}, []int64{2, 1})
+	assert.Equal(s.T(), []int64{top[0].ID, top[1].ID}, []int64{1, 2})
+	feedPosts(s.deps, "outage", feedNewest)
+	assert.Equal(s.T(), apiCacheStats{Hits: 1, Misses: 2, Size: 2}, s.deps.searches.stats())
+}
+
+func (s *HandlerTestSuite) TestSearchCacheStats() {
+	s.addPosts(apiTestPosts())
+	s.deps.searches = newSearchCache(10)
+	for i := 0; i < 2; i++ {
+		s.apiRequest(apiSearchHandler, "/api/v1/search?query=outage")
+	}
+	response := s.apiRequest(apiStatsHandler, "/api/v1/stats")
+	data := apiStatsResponse{}
+	assert.NoError(s.T(), json.Unmarshal(response.Body.Bytes(), &data))
+	assert.Equal(s.T(), apiCacheStats{Hits: 1, Misses: 1, Size: 1}, data.SearchCache)
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"html/template"
@@ -12,23 +13,22 @@ import (
 	"strings"
 	"time"
 
+	"github.com/albertyw/reaction-pics/linkcheck"
 	"github.com/albertyw/reaction-pics/tumblr"
-	"github.com/ikeikeikeike/go-sitemap-generator/v2/stm"
 	"github.com/newrelic/go-agent/v3/newrelic"
 	"github.com/pkg/errors"
 	"github.com/rollbar/rollbar-go"
 	"go.uber.org/zap"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 const (
 	maxResults = 20
+	// maxRequestBodySize is the most that handlers read of a request body
+	maxRequestBodySize = 64 << 10
 )
 
-type metaHeader struct {
-	Property string
-	Content  string
-}
-
 func relToAbsPath(path string) string {
 	_, filename, _, ok := runtime.Caller(0)
 	if !ok {
@@ -43,35 +43,57 @@ func indexHandler(w http.ResponseWriter, r *http.Request, d handlerDeps) {
 	if r.URL.Path != "/" && !strings.HasPrefix(r.URL.Path, "/post/") {
 		err := fmt.Errorf("file not found: %s", r.URL.Path)
 		d.logger.Warn(err)
-		rollbar.RequestError(rollbar.WARN, r, err)
+		rollbarRequestError(rollbar.WARN, r, err)
 		http.NotFound(w, r)
 		return
 	}
-	indexHandlerWithHeaders(w, r, d, []metaHeader{})
+	page := indexPage{}
+	if r.URL.Path == "/" {
+		results, err := searchResultsFromRequest(r, d)
+		if err != nil {
+			d.logger.Warn(err)
+			rollbarRequestError(rollbar.WARN, r, err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		page.Query = r.URL.Query().Get("query")
+		page.Results = &results
+	}
+	indexHandlerWithHeaders(w, r, d, page)
+}
+
+// indexPage is the data that is rendered into the index page template
+type indexPage struct {
+	pageMetadata
+	Query   string
+	Results *resultsPage
 }
 
-func indexHandlerWithHeaders(w http.ResponseWriter, r *http.Request, d handlerDeps, headers []metaHeader) {
+func indexHandlerWithHeaders(w http.ResponseWriter, r *http.Request, d handlerDeps, page indexPage) {
 	path := relToAbsPath("static/index.htm")
 	t, err := template.ParseFiles(path)
 	if err != nil {
 		err = errors.Wrap(err, "Cannot read post template")
 		d.logger.Error(err)
-		rollbar.RequestError(rollbar.ERR, r, err)
+		rollbarRequestError(rollbar.ERR, r, err)
 		http.Error(w, err.Error(), 500)
 		return
 	}
 	templateData := struct {
 		CacheString string
-		MetaHeaders []metaHeader
+		// BlankResult renders the result template that the browser fills in
+		// with search results
+		BlankResult tumblr.PostJSON
+		indexPage
 	}{
 		CacheString: d.appCacheString,
-		MetaHeaders: headers,
+		indexPage:   page,
 	}
 	err = t.Execute(w, templateData)
 	if err != nil {
 		err = errors.Wrap(err, "Cannot execute template")
 		d.logger.Error(err)
-		rollbar.RequestError(rollbar.ERR, r, err)
+		rollbarRequestError(rollbar.ERR, r, err)
 		http.Error(w, err.Error(), 500)
 		return
 	}
@@ -80,115 +102,82 @@ func indexHandlerWithHeaders(w http.ResponseWriter, r *http.Request, d handlerDe
 // searchHandler is an http handler to search data for keywords in json format
 // It matches the query against post titles and then ranks posts by number of likes
 func searchHandler(w http.ResponseWriter, r *http.Request, d handlerDeps) {
-	query := r.URL.Query().Get("query")
-	query = strings.ToLower(query)
-	queriedBoard := d.board.FilterBoard(query)
-	if query == "" {
-		queriedBoard.RandomizePosts()
-	}
-	offsetString := r.URL.Query().Get("offset")
-	offset, err := strconv.Atoi(offsetString)
+	results, err := searchResultsFromRequest(r, d)
 	if err != nil {
-		offset = 0
+		d.logger.Warn(err)
+		rollbarRequestError(rollbar.WARN, r, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
-	data := map[string]interface{}{
-		"offset":       offset,
-		"totalResults": len(queriedBoard.Posts),
+	if r.URL.Query().Get("query") == "" {
+		// Empty queries return random posts that differ on every request
+		w.Header().Set("Cache-Control", "no-store")
+	} else if checkBoardCache(w, r, d.board) {
+		return
 	}
-	queriedBoard.LimitBoard(offset, maxResults)
-	queriedBoard.SortPostsByLikes()
-	data["data"] = queriedBoard.PostsToJSON()
-	dataBytes, _ := json.Marshal(data)
+	dataBytes, _ := json.Marshal(results)
 	fmt.Fprint(w, string(dataBytes))
 }
 
+// imageFilterFromRequest reads image metadata search constraints from the
+// "type" ("static" or "animated") and "maxDuration" (milliseconds) parameters,
+// and validates them against a board
+func imageFilterFromRequest(r *http.Request, board *tumblr.Board) (tumblr.ImageFilter, error) {
+	filter := tumblr.ImageFilter{Type: r.URL.Query().Get("type")}
+	if maxDuration := r.URL.Query().Get("maxDuration"); maxDuration != "" {
+		var err error
+		filter.MaxDuration, err = strconv.Atoi(maxDuration)
+		if err != nil {
+			return filter, errors.Errorf("Invalid max duration %s", maxDuration)
+		}
+	}
+	return filter, board.ValidateImageFilter(filter)
+}
+
 // postDataHandler is an http handler to return post data by ID in json format
 func postDataHandler(w http.ResponseWriter, r *http.Request, d handlerDeps) {
-	pathStrings := strings.Split(r.URL.Path, "/")
-	postIDString := pathStrings[2]
-	postID, err := strconv.ParseInt(postIDString, 10, 64)
+	post, err := findPost(d.board, strings.Split(r.URL.Path, "/")[2])
 	if err != nil {
-		err = errors.Wrap(err, "Cannot parse post id")
 		d.logger.Warn(err)
-		rollbar.RequestError(rollbar.WARN, r, err)
+		rollbarRequestError(rollbar.WARN, r, err)
 		http.NotFound(w, r)
 		return
 	}
-	post := d.board.GetPostByID(postID)
-	if post == nil {
-		err = errors.New("Cannot find post")
-		d.logger.Warn(err)
-		rollbar.RequestError(rollbar.WARN, r, err)
-		http.NotFound(w, r)
+	if checkBoardCache(w, r, d.board) {
 		return
 	}
-	data := map[string]interface{}{
-		"offset":       0,
-		"totalResults": 1,
-		"data":         []tumblr.PostJSON{post.ToJSONStruct()},
-	}
-	marshalledPost, _ := json.Marshal(data)
+	marshalledPost, _ := json.Marshal(postResults(*post))
 	fmt.Fprint(w, string(marshalledPost))
 }
 
 // postHandler is an http handler that validates the correctness of a post url
 // and returns the index page html to render it correct
 func postHandler(w http.ResponseWriter, r *http.Request, d handlerDeps) {
-	pathStrings := strings.Split(r.URL.Path, "/")
-	postIDString := pathStrings[2]
-	postID, err := strconv.ParseInt(postIDString, 10, 64)
+	post, err := findPost(d.board, strings.Split(r.URL.Path, "/")[2])
 	if err != nil {
-		err = errors.Wrap(err, "Cannot parse post id")
 		d.logger.Warn(err)
-		rollbar.RequestError(rollbar.WARN, r, err)
+		rollbarRequestError(rollbar.WARN, r, err)
 		http.NotFound(w, r)
 		return
 	}
-	var post *tumblr.Post
-	for _, p := range d.board.Posts {
-		if p.ID == postID {
-			post = &p
-			break
-		}
-	}
-	if post == nil {
-		err = errors.New("Cannot find post")
-		d.logger.Warn(err)
-		rollbar.RequestError(rollbar.WARN, r, err)
-		http.NotFound(w, r)
-		return
-	}
-
-	headers := []metaHeader{
-		metaHeader{"og:title", post.Title},
-		metaHeader{"og:image", post.Image},
-	}
 
-	indexHandlerWithHeaders(w, r, d, headers)
+	results := postResults(*post)
+	page := indexPage{pageMetadata: postMetadata(*post), Results: &results}
+	indexHandlerWithHeaders(w, r, d, page)
 }
 
 // statsHandler returns internal stats about the reaction.pics DB as json
 func statsHandler(w http.ResponseWriter, r *http.Request, d handlerDeps) {
-	postCount := strconv.Itoa(len(d.board.Posts))
-	data := map[string]interface{}{
-		"postCount": postCount,
-		"keywords":  d.board.Keywords(),
+	if checkBoardCache(w, r, d.board) {
+		return
 	}
-	stats, _ := json.Marshal(data)
-	fmt.Fprint(w, string(stats))
-}
-
-// sitemapHandler returns a sitemap of reaction.pics as an xml file
-func sitemapHandler(w http.ResponseWriter, r *http.Request, d handlerDeps) {
-	sm := stm.NewSitemap(0)
-	sm.SetDefaultHost(os.Getenv("HOST"))
-
-	sm.Create()
-	sm.Add(stm.URL{{"loc", "/"}})
-	for _, url := range d.board.URLs() {
-		sm.Add(stm.URL{{"loc", url}})
+	stats := getStats(d)
+	data := map[string]interface{}{
+		"postCount": strconv.Itoa(stats.PostCount),
+		"keywords":  stats.Keywords,
 	}
-	w.Write(sm.XMLContent())
+	statsData, _ := json.Marshal(data)
+	fmt.Fprint(w, string(statsData))
 }
 
 // staticHandler returns static files
@@ -198,11 +187,7 @@ func staticHandler(w http.ResponseWriter, r *http.Request, _ handlerDeps) {
 }
 
 func timeHandler(w http.ResponseWriter, r *http.Request, _ handlerDeps) {
-	unixTime := int32(time.Now().Unix())
-	data := map[string]interface{}{
-		"unixtime": unixTime,
-	}
-	timeData, _ := json.Marshal(data)
+	timeData, _ := json.Marshal(getTime())
 	fmt.Fprint(w, string(timeData))
 }
 
@@ -211,8 +196,29 @@ func faviconHandler(w http.ResponseWriter, r *http.Request, _ handlerDeps) {
 	http.ServeFile(w, r, faviconPath)
 }
 
-func robotsTxtHandler(w http.ResponseWriter, r *http.Request, _ handlerDeps) {
-	fmt.Fprint(w, "")
+// startLinkChecker periodically checks post links in the background if
+// LINK_CHECK_INTERVAL is set
+func startLinkChecker(board *tumblr.Board, logger *zap.SugaredLogger) *linkcheck.Checker {
+	intervalString := os.Getenv("LINK_CHECK_INTERVAL")
+	if intervalString == "" {
+		return nil
+	}
+	interval, err := time.ParseDuration(intervalString)
+	if err != nil {
+		err = errors.Wrap(err, "Cannot parse LINK_CHECK_INTERVAL")
+		logger.Error(err)
+		rollbar.Error(rollbar.ERR, err)
+		return nil
+	}
+	autoHide := os.Getenv("LINK_CHECK_AUTOHIDE") == "true"
+	checker := linkcheck.NewChecker(nil)
+	checker.HistoryPath = os.Getenv("LINK_CHECK_HISTORY")
+	go func() {
+		err := checker.Run(context.Background(), board, interval, autoHide)
+		logger.Error(err)
+		rollbar.Error(rollbar.ERR, err)
+	}()
+	return checker
 }
 
 // Run starts up the HTTP server
@@ -220,7 +226,11 @@ func Run(newrelicApp *newrelic.Application, logger *zap.SugaredLogger) {
 	board := tumblr.InitializeBoard()
 	address := fmt.Sprintf(":%s", os.Getenv("PORT"))
 	logger.Infof("server listening on %s", address)
-	generator := newHandlerGenerator(board, newrelicApp, logger)
+	generator, err := newHandlerGenerator(board, newrelicApp, logger)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	generator.deps.linkChecker = startLinkChecker(board, logger)
 	http.Handle(generator.newHandler("/", indexHandler))
 	http.Handle(generator.newHandler("/favicon.ico", faviconHandler))
 	http.Handle(generator.newHandler("/robots.txt", robotsTxtHandler))
@@ -228,8 +238,35 @@ func Run(newrelicApp *newrelic.Application, logger *zap.SugaredLogger) {
 	http.Handle(generator.newHandler("/postdata/", postDataHandler))
 	http.Handle(generator.newHandler("/post/", postHandler))
 	http.Handle(generator.newHandler("/stats.json", statsHandler))
-	http.Handle(generator.newHandler("/sitemap.xml", sitemapHandler))
+	http.Handle(generator.newHandler("/oembed", oembedHandler))
+	http.Handle(generator.newHandler("/feed.rss", feedHandler("rss")))
+	http.Handle(generator.newHandler("/feed.atom", feedHandler("atom")))
+	http.Handle(generator.newHandler("/feed.json", feedHandler("json")))
+	http.Handle(generator.newHandler(sitemapPath, sitemapHandler))
+	http.Handle(generator.newHandler(sitemapChildPath, sitemapHandler))
 	http.Handle(generator.newHandler("/static/", staticHandler))
 	http.Handle(generator.newHandler("/time/", timeHandler))
-	http.ListenAndServe(address, nil)
+	for _, route := range apiRoutes() {
+		http.Handle(generator.newHandler(route.pattern, route.handler))
+	}
+	http.Handle(generator.newHandler(apiPrefix+"/openapi.json", openAPIHandler))
+	http.Handle(generator.newHandler(apiPrefix+"/", apiNotFoundHandler))
+	http.Handle(generator.newHandler("/graphql", graphqlHandler))
+	servingMetrics, err := serveMetrics(generator.deps.metrics, logger)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if !servingMetrics {
+		http.Handle(generator.newHandler(metricsPath, metricsHandler))
+	}
+	http.Handle(generator.newHandler(newRPCHandler(generator.deps)))
+	http.Handle(generator.newHandler("/admin/links", adminLinksHandler))
+	http.Handle(generator.newHandler("/integrations/slack/command", slackCommandHandler))
+	http.Handle(generator.newHandler("/integrations/slack/interactive", slackInteractiveHandler))
+	http.Handle(generator.newHandler("/integrations/discord", discordHandler))
+	http.Handle(generator.newHandler("/integrations/mattermost", chatHandler(mattermost{})))
+	http.Handle(generator.newHandler("/integrations/teams", chatHandler(teams{})))
+	http.Handle(generator.newHandler("/integrations/telegram", telegramHandler))
+	// h2c serves HTTP/2 without TLS so that gRPC clients can connect directly
+	http.ListenAndServe(address, h2c.NewHandler(http.DefaultServeMux, &http2.Server{}))
 }
@@ -2,6 +2,7 @@ package server
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -36,6 +37,13 @@ func (s *HandlerTestSuite) SetupTest() {
 	}
 }
 
+// addPosts adds fixture posts to the board of the suite
+func (s *HandlerTestSuite) addPosts(posts []tumblr.Post) {
+	for _, post := range posts {
+		s.deps.board.AddPost(post)
+	}
+}
+
 func (s *HandlerTestSuite) TestIndexFile() {
 	request, err := http.NewRequest("GET", "/", nil)
 	assert.NoError(s.T(), err)
@@ -51,6 +59,39 @@ func (s *HandlerTestSuite) TestIndexFile() {
 	assert.Contains(s.T(), response.Body.String(), s.deps.appCacheString)
 }
 
+func (s *HandlerTestSuite) TestIndexRendersResults() {
+	for i := 1; i <= maxResults+1; i++ {
+		s.deps.board.AddPost(tumblr.Post{
+			ID:    int64(i),
+			Title: fmt.Sprintf("Outage %d", i),
+			URL:   "https://reactionpics.tumblr.com/post/1",
+			Image: fmt.Sprintf("https://img.reaction.pics/file/reaction-pics/%d.gif", i),
+			Likes: int64(i),
+		})
+	}
+	request, err := http.NewRequest("GET", "/?query=outage", nil)
+	assert.NoError(s.T(), err)
+
+	response := httptest.NewRecorder()
+	indexHandler(response, request, s.deps)
+	assert.Equal(s.T(), response.Code, 200)
+	body := response.Body.String()
+	assert.Contains(s.T(), body, `data-rendered="true"`)
+	assert.Contains(s.T(), body, `value="outage"`)
+	assert.Contains(s.T(), body, `<h2><a class="result-title" href="/post/20/outage-20">Outage 20</a></h2>`)
+	assert.Contains(s.T(), body, `<img src="https://img.reaction.pics/file/reaction-pics/20.gif" class="result-img" loading="lazy" />`)
+	assert.Contains(s.T(), body, `<span class="result-like-count">20</span>`)
+	// The results and the template that the browser renders results with
+	// share the same markup
+	assert.Equal(s.T(), strings.Count(body, `<div class="result">`), maxResults+1)
+	assert.Contains(s.T(), body, `<template id="resultTemplate">
+<div class="result">
+  <h2><a class="result-title" href="">`)
+	assert.Contains(s.T(), body, `<p class="result-image" hidden><img class="result-img" loading="lazy" /></p>`)
+	assert.Contains(s.T(), body, `href="/?query=outage&amp;offset=20"`)
+	assert.Contains(s.T(), body, `<input type="hidden" id="totalResults" value="21">`)
+}
+
 func (s *HandlerTestSuite) TestOnlyIndexFile() {
 	request, err := http.NewRequest("GET", "/asdf", nil)
 	assert.NoError(s.T(), err)
@@ -119,6 +160,48 @@ func (s *HandlerTestSuite) TestSearchHandlerMalformedOffset() {
 	assert.Equal(s.T(), response.Body.String(), "{\"data\":[],\"offset\":0,\"totalResults\":0}")
 }
 
+func (s *HandlerTestSuite) TestSearchHandlerImageFilter() {
+	s.deps.board.AddPost(tumblr.Post{ID: 1, Title: "static", Meta: &tumblr.ImageMeta{Frames: 1}})
+	s.deps.board.AddPost(tumblr.Post{ID: 2, Title: "short", Meta: &tumblr.ImageMeta{Frames: 5, Duration: 500}})
+	s.deps.board.AddPost(tumblr.Post{ID: 3, Title: "long", Meta: &tumblr.ImageMeta{Frames: 50, Duration: 5000}})
+	request, err := http.NewRequest("GET", "/search?type=animated&maxDuration=1000", nil)
+	assert.NoError(s.T(), err)
+
+	response := httptest.NewRecorder()
+	searchHandler(response, request, s.deps)
+	assert.Equal(s.T(), response.Code, 200)
+	var data map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &data)
+	assert.Equal(s.T(), data["totalResults"], float64(1))
+	post := data["data"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(s.T(), post["title"], "short")
+	meta := post["meta"].(map[string]interface{})
+	assert.Equal(s.T(), meta["duration"], float64(500))
+}
+
+func (s *HandlerTestSuite) TestSearchHandlerInvalidImageFilter() {
+	for _, params := range []string{"type=video", "maxDuration=-1", "maxDuration=asdf"} {
+		request, err := http.NewRequest("GET", "/search?query=a&"+params, nil)
+		assert.NoError(s.T(), err)
+
+		response := httptest.NewRecorder()
+		searchHandler(response, request, s.deps)
+		assert.Equal(s.T(), response.Code, 400, params)
+		assert.Equal(s.T(), response.Header().Get("ETag"), "", params)
+	}
+}
+
+func (s *HandlerTestSuite) TestSearchHandlerNotIndexed() {
+	s.deps.board.AddPost(tumblr.Post{ID: 1, Title: "static"})
+	request, err := http.NewRequest("GET", "/search?query=static&type=static", nil)
+	assert.NoError(s.T(), err)
+
+	response := httptest.NewRecorder()
+	searchHandler(response, request, s.deps)
+	assert.Equal(s.T(), response.Code, 400)
+	assert.Contains(s.T(), response.Body.String(), "no images are indexed")
+}
+
 func (s *HandlerTestSuite) TestPostHandlerMalformed() {
 	request, err := http.NewRequest("GET", "/post/asdf", nil)
 	assert.NoError(s.T(), err)
@@ -154,6 +237,28 @@ func (s *HandlerTestSuite) TestPostHandler() {
 	assert.NotEqual(s.T(), len(body), 0)
 	assert.True(s.T(), strings.Contains(body, post.Title))
 	assert.True(s.T(), strings.Contains(body, post.Image))
+	assert.True(s.T(), strings.Contains(body, "/oembed?format=json"))
+	assert.True(s.T(), strings.Contains(body, `data-rendered="true"`))
+	assert.True(s.T(), strings.Contains(body, `<img src="`+post.Image+`"`))
+}
+
+func (s *HandlerTestSuite) TestPostHandlerEscapesMetadata() {
+	post := tumblr.Post{
+		ID:    1234,
+		Title: `</script><b>"Title`,
+		Image: "https://img.reaction.pics/file/reaction-pics/abcd.gif",
+	}
+	s.deps.board.AddPost(post)
+	request, err := http.NewRequest("GET", "/post/1234", nil)
+	assert.NoError(s.T(), err)
+
+	response := httptest.NewRecorder()
+	postHandler(response, request, s.deps)
+	assert.Equal(s.T(), response.Code, 200)
+	body := response.Body.String()
+	assert.False(s.T(), strings.Contains(body, post.Title))
+	assert.True(s.T(), strings.Contains(body, `<meta name="twitter:card" content="summary_large_image" />`))
+	assert.True(s.T(), strings.Contains(body, `"name":"\u003c/script\u003e\u003cb\u003e\"Title"`))
 }
 
 func (s *HandlerTestSuite) TestPostDataHandler() {
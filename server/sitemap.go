@@ -0,0 +1,180 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/albertyw/reaction-pics/tumblr"
+	"github.com/pkg/errors"
+	"github.com/rollbar/rollbar-go"
+)
+
+const (
+	// sitemapMaxURLs is the most urls that a sitemap file may contain
+	sitemapMaxURLs   = 50000
+	sitemapPath      = "/sitemap.xml"
+	sitemapChildPath = "/sitemaps/"
+	sitemapXMLNS     = "http://www.sitemaps.org/schemas/sitemap/0.9"
+	sitemapImageNS   = "http://www.google.com/schemas/sitemap-image/1.1"
+)
+
+// sitemapImage is a Google image sitemap entry
+type sitemapImage struct {
+	Loc string `xml:"image:loc"`
+}
+
+// sitemapURL is a url in a sitemap
+type sitemapURL struct {
+	Loc     string        `xml:"loc"`
+	LastMod string        `xml:"lastmod,omitempty"`
+	Image   *sitemapImage `xml:"image:image,omitempty"`
+}
+
+// sitemapURLSet is a sitemap file
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	ImageNS string       `xml:"xmlns:image,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+// sitemapEntry is a child sitemap in a sitemap index
+type sitemapEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+// sitemapIndex is a sitemap index file listing child sitemaps
+type sitemapIndex struct {
+	XMLName  xml.Name       `xml:"sitemapindex"`
+	XMLNS    string         `xml:"xmlns,attr"`
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+}
+
+// sitemapURLs returns the sitemap entries for the index page and every post.
+// Posts without a timestamp were last modified when the board was.
+func sitemapURLs(board *tumblr.Board, host string) []sitemapURL {
+	updated := board.Updated().UTC().Format(time.RFC3339)
+	urls := []sitemapURL{{Loc: host + "/"}}
+	for _, post := range *board.PostsToJSON() {
+		url := sitemapURL{Loc: host + post.InternalURL, LastMod: updated}
+		if post.Timestamp > 0 {
+			url.LastMod = time.Unix(post.Timestamp, 0).UTC().Format(time.RFC3339)
+		}
+		if post.Image != "" {
+			url.Image = &sitemapImage{Loc: post.Image}
+		}
+		urls = append(urls, url)
+	}
+	return urls
+}
+
+// marshalSitemap encodes a sitemap file with an xml header
+func marshalSitemap(v interface{}) ([]byte, error) {
+	data, err := xml.Marshal(v)
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot marshal sitemap")
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// buildSitemaps returns the sitemap files of a board by path. Boards with up
+// to maxURLs urls have a single sitemap, and larger boards have a sitemap
+// index linking to gzipped child sitemaps of up to maxURLs urls each.
+func buildSitemaps(board *tumblr.Board, host string, maxURLs int) (map[string][]byte, error) {
+	urls := sitemapURLs(board, host)
+	files := map[string][]byte{}
+	if len(urls) <= maxURLs {
+		data, err := marshalSitemap(sitemapURLSet{XMLNS: sitemapXMLNS, ImageNS: sitemapImageNS, URLs: urls})
+		files[sitemapPath] = data
+		return files, err
+	}
+	index := sitemapIndex{XMLNS: sitemapXMLNS}
+	lastMod := board.Updated().UTC().Format(time.RFC3339)
+	for i := 0; i*maxURLs < len(urls); i++ {
+		end := (i + 1) * maxURLs
+		if end > len(urls) {
+			end = len(urls)
+		}
+		data, err := marshalSitemap(sitemapURLSet{XMLNS: sitemapXMLNS, ImageNS: sitemapImageNS, URLs: urls[i*maxURLs : end]})
+		if err != nil {
+			return nil, err
+		}
+		var compressed bytes.Buffer
+		writer := gzip.NewWriter(&compressed)
+		writer.Write(data)
+		writer.Close()
+		path := fmt.Sprintf("%ssitemap-%d.xml.gz", sitemapChildPath, i+1)
+		files[path] = compressed.Bytes()
+		index.Sitemaps = append(index.Sitemaps, sitemapEntry{Loc: host + path, LastMod: lastMod})
+	}
+	data, err := marshalSitemap(index)
+	files[sitemapPath] = data
+	return files, err
+}
+
+// sitemapCache holds the sitemap files of the latest version of a board
+type sitemapCache struct {
+	mut     sync.Mutex
+	maxURLs int
+	version int64
+	files   map[string][]byte
+}
+
+// newSitemapCache returns an empty sitemap cache
+func newSitemapCache() *sitemapCache {
+	return &sitemapCache{maxURLs: sitemapMaxURLs, version: -1}
+}
+
+// get returns the sitemap files for a board, which are only rebuilt when the
+// board version changes
+func (c *sitemapCache) get(board *tumblr.Board) (map[string][]byte, error) {
+	if c == nil {
+		return buildSitemaps(board, os.Getenv("HOST"), sitemapMaxURLs)
+	}
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	version := board.Version()
+	if c.files != nil && c.version == version {
+		return c.files, nil
+	}
+	files, err := buildSitemaps(board, os.Getenv("HOST"), c.maxURLs)
+	if err != nil {
+		return nil, err
+	}
+	c.files = files
+	c.version = version
+	return files, nil
+}
+
+// sitemapHandler returns a sitemap of reaction.pics as an xml file, which is
+// a sitemap index for large boards with child sitemaps under /sitemaps/
+func sitemapHandler(w http.ResponseWriter, r *http.Request, d handlerDeps) {
+	files, err := d.sitemaps.get(d.board)
+	if err != nil {
+		d.logger.Error(err)
+		rollbarRequestError(rollbar.ERR, r, err)
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	data, ok := files[r.URL.Path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if checkBoardCache(w, r, d.board) {
+		return
+	}
+	if r.URL.Path == sitemapPath {
+		w.Header().Set("Content-Type", "application/xml")
+	} else {
+		w.Header().Set("Content-Type", "application/x-gzip")
+	}
+	w.Write(data)
+}
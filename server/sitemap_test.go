@@ -0,0 +1,111 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/albertyw/reaction-pics/tumblr"
+	"github.com/stretchr/testify/assert"
+)
+
+var sitemapTestPosts = []tumblr.Post{
+	{ID: 1, Title: "Outage", Image: "https://example.com/1.gif", Timestamp: 1600000000},
+	{ID: 2, Title: "Deploy", Image: "https://example.com/2.gif"},
+}
+
+func (s *HandlerTestSuite) TestBuildSitemapsSingle() {
+	s.addPosts(sitemapTestPosts)
+	files, err := buildSitemaps(s.deps.board, "https://www.reaction.pics", sitemapMaxURLs)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), len(files), 1)
+
+	urlset := sitemapURLSet{}
+	assert.NoError(s.T(), xml.Unmarshal(files[sitemapPath], &urlset))
+	assert.Equal(s.T(), len(urlset.URLs), 3)
+	assert.Equal(s.T(), urlset.URLs[0].Loc, "https://www.reaction.pics/")
+	assert.Equal(s.T(), urlset.URLs[1].Loc, "https://www.reaction.pics/post/1/outage")
+	assert.Equal(s.T(), urlset.URLs[1].LastMod, "2020-09-13T12:26:40Z")
+	assert.Equal(s.T(), urlset.URLs[2].LastMod, s.deps.board.Updated().UTC().Format(time.RFC3339))
+	assert.Contains(s.T(), string(files[sitemapPath]), "<image:image><image:loc>https://example.com/1.gif</image:loc></image:image>")
+	assert.Contains(s.T(), string(files[sitemapPath]), `xmlns:image="`+sitemapImageNS+`"`)
+}
+
+func (s *HandlerTestSuite) TestSitemapURLsWithoutTimestamp() {
+	s.deps.board.AddPost(tumblr.Post{ID: 3, Title: "Rollback", Image: "https://example.com/3.gif"})
+	urls := sitemapURLs(s.deps.board, "https://www.reaction.pics")
+	assert.Equal(s.T(), len(urls), 2)
+	assert.Equal(s.T(), urls[0].LastMod, "")
+	assert.Equal(s.T(), urls[1].Loc, "https://www.reaction.pics/post/3/rollback")
+	assert.NotEqual(s.T(), urls[1].LastMod, "")
+	assert.Equal(s.T(), urls[1].LastMod, s.deps.board.Updated().UTC().Format(time.RFC3339))
+}
+
+func (s *HandlerTestSuite) TestBuildSitemapsIndex() {
+	s.addPosts(sitemapTestPosts)
+	files, err := buildSitemaps(s.deps.board, "https://www.reaction.pics", 2)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), len(files), 3)
+
+	index := sitemapIndex{}
+	assert.NoError(s.T(), xml.Unmarshal(files[sitemapPath], &index))
+	assert.Equal(s.T(), len(index.Sitemaps), 2)
+	assert.Equal(s.T(), index.Sitemaps[1].Loc, "https://www.reaction.pics/sitemaps/sitemap-2.xml.gz")
+	assert.NotEqual(s.T(), index.Sitemaps[1].LastMod, "")
+
+	reader, err := gzip.NewReader(bytes.NewReader(files["/sitemaps/sitemap-2.xml.gz"]))
+	assert.NoError(s.T(), err)
+	data, err := ioutil.ReadAll(reader)
+	assert.NoError(s.T(), err)
+	urlset := sitemapURLSet{}
+	assert.NoError(s.T(), xml.Unmarshal(data, &urlset))
+	assert.Equal(s.T(), len(urlset.URLs), 1)
+	assert.Equal(s.T(), urlset.URLs[0].Loc, "https://www.reaction.pics/post/2/deploy")
+}
+
+func (s *HandlerTestSuite) TestSitemapCache() {
+	s.addPosts(sitemapTestPosts)
+	cache := newSitemapCache()
+	files, err := cache.get(s.deps.board)
+	assert.NoError(s.T(), err)
+	cached, err := cache.get(s.deps.board)
+	assert.NoError(s.T(), err)
+	assert.True(s.T(), &files[sitemapPath][0] == &cached[sitemapPath][0])
+
+	s.deps.board.AddPost(tumblr.Post{ID: 3, Title: "Merge"})
+	updated, err := cache.get(s.deps.board)
+	assert.NoError(s.T(), err)
+	assert.Contains(s.T(), string(updated[sitemapPath]), "/post/3/merge")
+}
+
+func (s *HandlerTestSuite) TestSitemapHandlerIndex() {
+	cache := newSitemapCache()
+	cache.maxURLs = 2
+	s.addPosts(sitemapTestPosts)
+	s.deps.sitemaps = cache
+
+	request, err := http.NewRequest("GET", "/sitemap.xml", nil)
+	assert.NoError(s.T(), err)
+	response := httptest.NewRecorder()
+	sitemapHandler(response, request, s.deps)
+	assert.Equal(s.T(), response.Code, 200)
+	assert.Equal(s.T(), response.Header().Get("Content-Type"), "application/xml")
+	assert.Contains(s.T(), response.Body.String(), "<sitemapindex")
+
+	request, err = http.NewRequest("GET", "/sitemaps/sitemap-1.xml.gz", nil)
+	assert.NoError(s.T(), err)
+	response = httptest.NewRecorder()
+	sitemapHandler(response, request, s.deps)
+	assert.Equal(s.T(), response.Code, 200)
+	assert.Equal(s.T(), response.Header().Get("Content-Type"), "application/x-gzip")
+
+	request, err = http.NewRequest("GET", "/sitemaps/sitemap-3.xml.gz", nil)
+	assert.NoError(s.T(), err)
+	response = httptest.NewRecorder()
+	sitemapHandler(response, request, s.deps)
+	assert.Equal(s.T(), response.Code, 404)
+}
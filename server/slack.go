@@ -0,0 +1,257 @@
+package server
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rollbar/rollbar-go"
+)
+
+const (
+	slackSignatureVersion = "v0"
+	slackTimestampWindow  = 5 * time.Minute
+	slackActionShuffle    = "shuffle"
+	slackActionSend       = "send"
+	slackResponseTimeout  = 5 * time.Second
+)
+
+// verifySlackRequest checks the HMAC signature that Slack sends with a
+// request body, and that the request was signed recently
+func verifySlackRequest(header http.Header, body []byte, secret string, now time.Time) error {
+	if secret == "" {
+		return errors.New("Slack signing secret is not configured")
+	}
+	timestampString := header.Get("X-Slack-Request-Timestamp")
+	timestamp, err := strconv.ParseInt(timestampString, 10, 64)
+	if err != nil {
+		return errors.Wrap(err, "Cannot parse Slack timestamp")
+	}
+	age := now.Sub(time.Unix(timestamp, 0))
+	if age > slackTimestampWindow || age < -slackTimestampWindow {
+		return errors.New("Slack timestamp is outside of the allowed window")
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s:%s:%s", slackSignatureVersion, timestampString, body)
+	expected := slackSignatureVersion + "=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(header.Get("X-Slack-Signature"))) {
+		return errors.New("Slack signature does not match")
+	}
+	return nil
+}
+
+// readSlackRequest reads and verifies the body of a Slack request
+func readSlackRequest(w http.ResponseWriter, r *http.Request) (url.Values, error) {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot read Slack request")
+	}
+	err = verifySlackRequest(r.Header, body, os.Getenv("SLACK_SIGNING_SECRET"), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return url.ParseQuery(string(body))
+}
+
+// slackText is a Block Kit text object
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// slackElement is a Block Kit block element
+type slackElement struct {
+	Type     string     `json:"type"`
+	Text     *slackText `json:"text,omitempty"`
+	ActionID string     `json:"action_id,omitempty"`
+	Value    string     `json:"value,omitempty"`
+	Style    string     `json:"style,omitempty"`
+}
+
+// slackBlock is a Block Kit layout block
+type slackBlock struct {
+	Type     string         `json:"type"`
+	Text     *slackText     `json:"text,omitempty"`
+	Title    *slackText     `json:"title,omitempty"`
+	ImageURL string         `json:"image_url,omitempty"`
+	AltText  string         `json:"alt_text,omitempty"`
+	Elements []slackElement `json:"elements,omitempty"`
+}
+
+// slackMessage is a Slack message in response to a command or action
+type slackMessage struct {
+	ResponseType    string       `json:"response_type,omitempty"`
+	ReplaceOriginal bool         `json:"replace_original,omitempty"`
+	DeleteOriginal  bool         `json:"delete_original,omitempty"`
+	Text            string       `json:"text"`
+	Blocks          []slackBlock `json:"blocks,omitempty"`
+}
+
+// slackState is the search state stored in the value of message buttons
+type slackState struct {
+	Query  string `json:"query"`
+	PostID int64  `json:"postID"`
+}
+
+// slackPostBlocks returns the blocks showing a post
+func slackPostBlocks(reply chatReply) []slackBlock {
+	link := fmt.Sprintf("<%s|%s>", reply.PostURL(), reply.Title())
+	return []slackBlock{
+		{
+			Type:     "image",
+			Title:    &slackText{Type: "plain_text", Text: reply.Title()},
+			ImageURL: reply.ImageURL(),
+			AltText:  reply.Title(),
+		},
+		{
+			Type: "section",
+			Text: &slackText{Type: "mrkdwn", Text: link},
+		},
+	}
+}
+
+// slackPreview returns an ephemeral message previewing a post, with buttons
+// to shuffle to another post or send it to the channel
+func slackPreview(reply chatReply) slackMessage {
+	if !reply.Found() {
+		return slackMessage{
+			ResponseType: "ephemeral",
+			Text:         reply.NotFoundText(),
+		}
+	}
+	state, _ := json.Marshal(slackState{Query: reply.Query, PostID: reply.Post.ID})
+	blocks := slackPostBlocks(reply)
+	blocks = append(blocks, slackBlock{
+		Type: "actions",
+		Elements: []slackElement{
+			{
+				Type:     "button",
+				Text:     &slackText{Type: "plain_text", Text: "Shuffle"},
+				ActionID: slackActionShuffle,
+				Value:    string(state),
+			},
+			{
+				Type:     "button",
+				Text:     &slackText{Type: "plain_text", Text: "Send"},
+				ActionID: slackActionSend,
+				Value:    string(state),
+				Style:    "primary",
+			},
+		},
+	})
+	return slackMessage{
+		ResponseType: "ephemeral",
+		Text:         reply.Title(),
+		Blocks:       blocks,
+	}
+}
+
+// writeSlackMessage writes a message as the json response to Slack
+func writeSlackMessage(w http.ResponseWriter, message slackMessage) {
+	data, _ := json.Marshal(message)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// sendSlackMessage posts a message to a Slack response url
+func sendSlackMessage(responseURL string, message slackMessage) error {
+	data, _ := json.Marshal(message)
+	client := http.Client{Timeout: slackResponseTimeout}
+	response, err := client.Post(responseURL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return errors.Wrap(err, "Cannot send Slack message")
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return errors.Errorf("Slack response url returned status %d", response.StatusCode)
+	}
+	return nil
+}
+
+// slackCommandHandler responds to a Slack slash command with a preview of
+// the top post matching the command text
+func slackCommandHandler(w http.ResponseWriter, r *http.Request, d handlerDeps) {
+	form, err := readSlackRequest(w, r)
+	if err != nil {
+		d.logger.Warn(err)
+		rollbarRequestError(rollbar.WARN, r, err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	reply := newChatReply(d.board, form.Get("text"))
+	writeSlackMessage(w, slackPreview(reply))
+}
+
+// slackInteractivePayload is the part of a Slack block_actions payload that
+// is used for handling button clicks
+type slackInteractivePayload struct {
+	Type        string `json:"type"`
+	ResponseURL string `json:"response_url"`
+	Actions     []struct {
+		ActionID string `json:"action_id"`
+		Value    string `json:"value"`
+	} `json:"actions"`
+}
+
+// slackInteractiveHandler handles the shuffle and send buttons of a preview
+func slackInteractiveHandler(w http.ResponseWriter, r *http.Request, d handlerDeps) {
+	form, err := readSlackRequest(w, r)
+	if err != nil {
+		d.logger.Warn(err)
+		rollbarRequestError(rollbar.WARN, r, err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	payload := slackInteractivePayload{}
+	err = json.Unmarshal([]byte(form.Get("payload")), &payload)
+	if err != nil || len(payload.Actions) == 0 {
+		err = errors.New("Cannot parse Slack interactive payload")
+		d.logger.Warn(err)
+		rollbarRequestError(rollbar.WARN, r, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	action := payload.Actions[0]
+	state := slackState{}
+	json.Unmarshal([]byte(action.Value), &state)
+
+	var message slackMessage
+	switch action.ActionID {
+	case slackActionShuffle:
+		reply := chatReply{Query: state.Query, Post: d.board.GetPostByID(state.PostID)}
+		message = slackPreview(reply.shuffle(d.board))
+		message.ReplaceOriginal = true
+	case slackActionSend:
+		reply := chatReply{Query: state.Query, Post: d.board.GetPostByID(state.PostID)}
+		if !reply.Found() {
+			http.Error(w, "Cannot find post", http.StatusNotFound)
+			return
+		}
+		message = slackMessage{
+			ResponseType:   "in_channel",
+			DeleteOriginal: true,
+			Text:           reply.Title(),
+			Blocks:         slackPostBlocks(reply),
+		}
+	default:
+		http.Error(w, "Unknown action", http.StatusBadRequest)
+		return
+	}
+	err = sendSlackMessage(payload.ResponseURL, message)
+	if err != nil {
+		d.logger.Error(err)
+		rollbarRequestError(rollbar.ERR, r, err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+}
@@ -0,0 +1,178 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testSlackSecret = "8f742231b10e8888abcd99yyyzzz85a5"
+
+// Example request from https://api.slack.com/authentication/verifying-requests-from-slack
+const slackExampleBody = "token=xyzz0WbapA4vBCDEFasx0q6G&team_id=T1DC2JH3J&team_domain=testteamnow&channel_id=G8PSS9T3V&channel_name=foobar&user_id=U2CERLKJA&user_name=roadrunner&command=%2Fwebhook-collect&text=&response_url=https%3A%2F%2Fhooks.slack.com%2Fcommands%2FT1DC2JH3J%2F397700885554%2F96rGlfmibIGlgcZRskXaIFfN&trigger_id=398738663015.47445629121.803a0bc887a14d10d2c447fce8b6703c"
+
+func slackHeaders(body string, timestamp time.Time) http.Header {
+	timestampString := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(testSlackSecret))
+	fmt.Fprintf(mac, "v0:%s:%s", timestampString, body)
+	header := http.Header{}
+	header.Set("X-Slack-Request-Timestamp", timestampString)
+	header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return header
+}
+
+func slackRequest(t *testing.T, path, body string) *http.Request {
+	request, err := http.NewRequest("POST", path, strings.NewReader(body))
+	assert.NoError(t, err)
+	request.Header = slackHeaders(body, time.Now())
+	return request
+}
+
+func setSlackSecret() func() {
+	origSecret := os.Getenv("SLACK_SIGNING_SECRET")
+	os.Setenv("SLACK_SIGNING_SECRET", testSlackSecret)
+	return func() { os.Setenv("SLACK_SIGNING_SECRET", origSecret) }
+}
+
+func TestVerifySlackRequestExample(t *testing.T) {
+	header := http.Header{}
+	header.Set("X-Slack-Request-Timestamp", "1531420618")
+	header.Set("X-Slack-Signature", "v0=a2114d57b48eac39b9ad189dd8316235a7b4a8d21a10bd27519666489c69b503")
+	now := time.Unix(1531420618, 0).Add(time.Minute)
+	err := verifySlackRequest(header, []byte(slackExampleBody), testSlackSecret, now)
+	assert.NoError(t, err)
+}
+
+func TestVerifySlackRequestInvalid(t *testing.T) {
+	now := time.Now()
+	body := []byte("text=outage")
+	header := slackHeaders(string(body), now)
+
+	assert.NoError(t, verifySlackRequest(header, body, testSlackSecret, now))
+	assert.Error(t, verifySlackRequest(header, body, "", now))
+	assert.Error(t, verifySlackRequest(header, body, "wrong secret", now))
+	assert.Error(t, verifySlackRequest(header, []byte("text=other"), testSlackSecret, now))
+	assert.Error(t, verifySlackRequest(header, body, testSlackSecret, now.Add(10*time.Minute)))
+	assert.Error(t, verifySlackRequest(header, body, testSlackSecret, now.Add(-10*time.Minute)))
+	header.Set("X-Slack-Request-Timestamp", "asdf")
+	assert.Error(t, verifySlackRequest(header, body, testSlackSecret, now))
+}
+
+func (s *HandlerTestSuite) TestSlackCommandHandler() {
+	s.addPosts(chatTestPosts)
+	defer setSlackSecret()()
+	body, err := ioutil.ReadFile("testdata/slack_command.txt")
+	assert.NoError(s.T(), err)
+
+	response := httptest.NewRecorder()
+	slackCommandHandler(response, slackRequest(s.T(), "/integrations/slack/command", string(body)), s.deps)
+	assert.Equal(s.T(), response.Code, 200)
+	message := slackMessage{}
+	assert.NoError(s.T(), json.Unmarshal(response.Body.Bytes(), &message))
+	assert.Equal(s.T(), message.ResponseType, "ephemeral")
+	assert.Equal(s.T(), message.Text, "Outage one")
+	assert.Equal(s.T(), message.Blocks[0].ImageURL, "https://example.com/1.gif")
+	actions := message.Blocks[2].Elements
+	assert.Equal(s.T(), actions[0].ActionID, slackActionShuffle)
+	assert.Equal(s.T(), actions[1].ActionID, slackActionSend)
+	assert.Equal(s.T(), actions[1].Value, `{"query":"outage","postID":1}`)
+}
+
+func (s *HandlerTestSuite) TestSlackCommandHandlerNoResults() {
+	s.addPosts(chatTestPosts)
+	defer setSlackSecret()()
+	response := httptest.NewRecorder()
+	slackCommandHandler(response, slackRequest(s.T(), "/integrations/slack/command", "text=asdf"), s.deps)
+	assert.Equal(s.T(), response.Code, 200)
+	assert.Contains(s.T(), response.Body.String(), "No reactions found")
+}
+
+func (s *HandlerTestSuite) TestSlackCommandHandlerUnsigned() {
+	s.addPosts(chatTestPosts)
+	defer setSlackSecret()()
+	request := slackRequest(s.T(), "/integrations/slack/command", "text=outage")
+	request.Header.Set("X-Slack-Signature", "v0=asdf")
+	response := httptest.NewRecorder()
+	slackCommandHandler(response, request, s.deps)
+	assert.Equal(s.T(), response.Code, 401)
+}
+
+func (s *HandlerTestSuite) TestSlackCommandHandlerTooLarge() {
+	s.addPosts(chatTestPosts)
+	defer setSlackSecret()()
+	request := slackRequest(s.T(), "/integrations/slack/command", "text="+strings.Repeat("a", maxRequestBodySize))
+	response := httptest.NewRecorder()
+	slackCommandHandler(response, request, s.deps)
+	assert.Equal(s.T(), response.Code, 401)
+}
+
+// slackInteractiveRequest returns a request with the recorded interactive
+// payload and a stand-in server for its response url
+func slackInteractiveRequest(t *testing.T, actionID string) (*http.Request, *httptest.Server, chan slackMessage) {
+	messages := make(chan slackMessage, 1)
+	responseServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		message := slackMessage{}
+		json.NewDecoder(r.Body).Decode(&message)
+		messages <- message
+	}))
+	payload, err := ioutil.ReadFile("testdata/slack_interactive.json")
+	assert.NoError(t, err)
+	payloadString := strings.Replace(string(payload), "RESPONSE_URL", responseServer.URL, 1)
+	payloadString = strings.Replace(payloadString, "ACTION_ID", actionID, 1)
+	body := url.Values{"payload": {payloadString}}.Encode()
+	return slackRequest(t, "/integrations/slack/interactive", body), responseServer, messages
+}
+
+func (s *HandlerTestSuite) TestSlackInteractiveShuffle() {
+	s.addPosts(chatTestPosts)
+	defer setSlackSecret()()
+	request, responseServer, messages := slackInteractiveRequest(s.T(), slackActionShuffle)
+	defer responseServer.Close()
+
+	response := httptest.NewRecorder()
+	slackInteractiveHandler(response, request, s.deps)
+	assert.Equal(s.T(), response.Code, 200)
+	message := <-messages
+	assert.True(s.T(), message.ReplaceOriginal)
+	assert.Equal(s.T(), message.Text, "Outage two")
+	assert.Equal(s.T(), len(message.Blocks), 3)
+}
+
+func (s *HandlerTestSuite) TestSlackInteractiveSend() {
+	s.addPosts(chatTestPosts)
+	defer setSlackSecret()()
+	request, responseServer, messages := slackInteractiveRequest(s.T(), slackActionSend)
+	defer responseServer.Close()
+
+	response := httptest.NewRecorder()
+	slackInteractiveHandler(response, request, s.deps)
+	assert.Equal(s.T(), response.Code, 200)
+	message := <-messages
+	assert.Equal(s.T(), message.ResponseType, "in_channel")
+	assert.True(s.T(), message.DeleteOriginal)
+	assert.Equal(s.T(), message.Text, "Outage one")
+	assert.Equal(s.T(), len(message.Blocks), 2)
+}
+
+func (s *HandlerTestSuite) TestSlackInteractiveMalformed() {
+	s.addPosts(chatTestPosts)
+	defer setSlackSecret()()
+	response := httptest.NewRecorder()
+	request := slackRequest(s.T(), "/integrations/slack/interactive", "payload=asdf")
+	slackInteractiveHandler(response, request, s.deps)
+	assert.Equal(s.T(), response.Code, 400)
+}
@@ -1,7 +1,6 @@
 const axios = require('axios');
 const LogFit = require('logfit');
 const Rollbar = require('rollbar');
-const LazyLoad = require('vanilla-lazyload');
 const varsnap = require('varsnap');
 
 const rollbarConfig = {
@@ -27,7 +26,6 @@ varsnap.updateConfig({
   consumerToken: process.env.VARSNAP_CONSUMER_TOKEN,
 });
 
-const lazyLoadInstance = new LazyLoad({});
 let searchCancel = undefined;
 
 function getJSON(url, params, cancellable) {
@@ -131,32 +129,35 @@ function addResults(data) {
   if (paginateNextElement !== null) {
     paginateNextElement.addEventListener('click', paginateNext);
   }
-  lazyLoadInstance.update();
   return resultHTML;
 }
 addResults = varsnap(addResults);
 
-function paginateNext() {
+function paginateNext(event) {
+  event.preventDefault();
   let offset = parseInt(document.getElementById('offset').value, 10);
   offset += parseInt(document.getElementById('paginateCount').value, 10);
   updateResults(getQuery(), offset);
 }
 
+// addResult renders a post with the result template from the server, so
+// that results look the same whether they are rendered by the server or here
 function addResult(postData) {
-  let postHTML = '<div class="result">';
-  postHTML += '<h2>';
-  if (postData.url) postHTML += '<a href="' + postData.internalURL + '">';
-  postHTML += postData.title;
-  if (postData.url) postHTML += '</a></h2>';
-  if (postData.image) postHTML += '<p><img data-src="' + postData.image + '" class="result-img lazy" /></p>';
+  const template = document.getElementById('resultTemplate');
+  const result = template.content.firstElementChild.cloneNode(true);
+  const title = result.querySelector('.result-title');
+  title.href = postData.internalURL;
+  title.textContent = postData.title;
+  if (postData.image) {
+    result.querySelector('.result-image').hidden = false;
+    result.querySelector('.result-img').src = postData.image;
+  }
   if (postData.likes) {
-      postHTML += '<p><a href="#" id="likes" class="btn btn-success disabled">';
-      postHTML += postData.likes + ' <span class="glyphicon glyphicon-thumbs-up" aria-hidden="true"></span>';
-      postHTML += '</a></p>';
-      postHTML += '<p><a href="' + postData.url + '">Original</a></p>';
+    result.querySelector('.result-likes').hidden = false;
+    result.querySelector('.result-like-count').textContent = postData.likes;
+    result.querySelector('.result-original').href = postData.url;
   }
-  postHTML += '</div>';
-  return postHTML;
+  return result.outerHTML;
 }
 addResult = varsnap(addResult);
 
@@ -179,14 +180,28 @@ function getParameterByName(url, name) {
 }
 getParameterByName = varsnap(getParameterByName);
 
+// hydrateResults takes over results that were rendered by the server
+function hydrateResults() {
+  const paginateNextElement = document.getElementById('paginateNext');
+  if (paginateNextElement !== null) {
+    paginateNextElement.addEventListener('click', paginateNext);
+  }
+}
+
 document.addEventListener('DOMContentLoaded', function() {
   const query = getParameterByName(window.location.href, 'query');
   if (query !== undefined && query !== '') {
     document.getElementById('query').value = query;
   }
   document.getElementById('query').addEventListener('input', () => updateResults(getQuery()));
+  document.getElementById('search').addEventListener('submit', (event) => {
+    event.preventDefault();
+    updateResults(getQuery());
+  });
   const urlPath = window.location.pathname.split('/');
-  if (urlPath[1] === 'post') {
+  if (document.getElementById('results').dataset.rendered === 'true') {
+    hydrateResults();
+  } else if (urlPath[1] === 'post') {
     showPost(urlPath[2]);
   } else {
     updateResults(getQuery());
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"html"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const teamsHeroCard = "application/vnd.microsoft.card.hero"
+
+// teamsMention matches the mention of the webhook at the start of a message
+var teamsMention = regexp.MustCompile(`<at>.*?</at>`)
+
+// verifyTeamsRequest checks the HMAC signature that Microsoft Teams sends
+// with the body of an outgoing webhook request
+func verifyTeamsRequest(header http.Header, body []byte, secret string) error {
+	key, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil || len(key) == 0 {
+		return errors.New("Teams secret is not configured")
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(body)
+	expected := "HMAC " + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(header.Get("Authorization"))) {
+		return errors.New("Teams signature does not match")
+	}
+	return nil
+}
+
+// teamsImage is an image in a Teams card
+type teamsImage struct {
+	URL string `json:"url"`
+}
+
+// teamsAction is a button in a Teams card
+type teamsAction struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+// teamsCard is the content of a Teams hero card
+type teamsCard struct {
+	Title   string        `json:"title"`
+	Images  []teamsImage  `json:"images"`
+	Buttons []teamsAction `json:"buttons"`
+}
+
+// teamsAttachment is a card attached to a Teams message
+type teamsAttachment struct {
+	ContentType string    `json:"contentType"`
+	Content     teamsCard `json:"content"`
+}
+
+// teamsMessage is a Teams message in response to an outgoing webhook
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Text        string            `json:"text,omitempty"`
+	Attachments []teamsAttachment `json:"attachments,omitempty"`
+}
+
+// teams answers Microsoft Teams outgoing webhooks
+type teams struct{}
+
+// verify checks the signature of an outgoing webhook request
+func (teams) verify(r *http.Request, body []byte) error {
+	return verifyTeamsRequest(r.Header, body, os.Getenv("TEAMS_SECRET"))
+}
+
+// query returns the text of the message without the mention of the webhook
+func (teams) query(body []byte) (string, error) {
+	activity := struct {
+		Text string `json:"text"`
+	}{}
+	err := json.Unmarshal(body, &activity)
+	if err != nil {
+		return "", err
+	}
+	text := teamsMention.ReplaceAllString(activity.Text, "")
+	return strings.Join(strings.Fields(html.UnescapeString(text)), " "), nil
+}
+
+// render returns a message with a hero card of the post, or a text message
+// if there is no post
+func (teams) render(reply chatReply) interface{} {
+	if !reply.Found() {
+		return teamsMessage{Type: "message", Text: reply.NotFoundText()}
+	}
+	return teamsMessage{
+		Type: "message",
+		Attachments: []teamsAttachment{{
+			ContentType: teamsHeroCard,
+			Content: teamsCard{
+				Title:   reply.Title(),
+				Images:  []teamsImage{{URL: reply.ImageURL()}},
+				Buttons: []teamsAction{{Type: "openUrl", Title: "View", Value: reply.PostURL()}},
+			},
+		}},
+	}
+}
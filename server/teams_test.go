@@ -0,0 +1,84 @@
+package server
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testTeamsSecret = base64.StdEncoding.EncodeToString([]byte("teams secret"))
+
+func teamsRequest(t *testing.T, body []byte) *http.Request {
+	request, err := http.NewRequest("POST", "/integrations/teams", bytes.NewReader(body))
+	assert.NoError(t, err)
+	mac := hmac.New(sha256.New, []byte("teams secret"))
+	mac.Write(body)
+	request.Header.Set("Authorization", "HMAC "+base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	return request
+}
+
+func setTeamsSecret() func() {
+	origSecret := os.Getenv("TEAMS_SECRET")
+	os.Setenv("TEAMS_SECRET", testTeamsSecret)
+	return func() { os.Setenv("TEAMS_SECRET", origSecret) }
+}
+
+func TestVerifyTeamsRequest(t *testing.T) {
+	body := []byte(`{"text":"outage"}`)
+	request := teamsRequest(t, body)
+
+	assert.NoError(t, verifyTeamsRequest(request.Header, body, testTeamsSecret))
+	assert.Error(t, verifyTeamsRequest(request.Header, body, ""))
+	assert.Error(t, verifyTeamsRequest(request.Header, body, "not base64!"))
+	assert.Error(t, verifyTeamsRequest(request.Header, []byte(`{"text":"other"}`), testTeamsSecret))
+	request.Header.Del("Authorization")
+	assert.Error(t, verifyTeamsRequest(request.Header, body, testTeamsSecret))
+}
+
+func TestTeamsQuery(t *testing.T) {
+	query, err := teams{}.query([]byte(`{"text":"<at>Reactions</at> outage&nbsp;one\n"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, query, "outage one")
+	_, err = teams{}.query([]byte("asdf"))
+	assert.Error(t, err)
+}
+
+func (s *HandlerTestSuite) TestTeamsHandler() {
+	s.addPosts(chatTestPosts)
+	defer setTeamsSecret()()
+	body, err := ioutil.ReadFile("testdata/teams_message.json")
+	assert.NoError(s.T(), err)
+
+	response := httptest.NewRecorder()
+	chatHandler(teams{})(response, teamsRequest(s.T(), body), s.deps)
+	assert.Equal(s.T(), response.Code, 200)
+	message := teamsMessage{}
+	assert.NoError(s.T(), json.Unmarshal(response.Body.Bytes(), &message))
+	assert.Equal(s.T(), message.Type, "message")
+	attachment := message.Attachments[0]
+	assert.Equal(s.T(), attachment.ContentType, teamsHeroCard)
+	assert.Equal(s.T(), attachment.Content.Title, "Outage one")
+	assert.Equal(s.T(), attachment.Content.Images[0].URL, "https://example.com/1.gif")
+	assert.Equal(s.T(), attachment.Content.Buttons[0].Value, os.Getenv("HOST")+"/post/1/outage-one")
+}
+
+func (s *HandlerTestSuite) TestTeamsHandlerUnsigned() {
+	s.addPosts(chatTestPosts)
+	defer setTeamsSecret()()
+	body := []byte(`{"text":"outage"}`)
+	request := teamsRequest(s.T(), body)
+	request.Header.Set("Authorization", "HMAC asdf")
+
+	response := httptest.NewRecorder()
+	chatHandler(teams{})(response, request, s.deps)
+	assert.Equal(s.T(), response.Code, 401)
+}
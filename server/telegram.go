@@ -0,0 +1,190 @@
+package server
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/albertyw/reaction-pics/tumblr"
+	"github.com/pkg/errors"
+	"github.com/rollbar/rollbar-go"
+)
+
+const (
+	telegramDefaultAPIURL = "https://api.telegram.org"
+	telegramCacheTime     = 300
+	telegramTimeout       = 5 * time.Second
+)
+
+// verifyTelegramRequest checks the secret token that Telegram sends with
+// webhook requests
+func verifyTelegramRequest(header http.Header, secret string) error {
+	if secret == "" {
+		return errors.New("Telegram secret token is not configured")
+	}
+	token := header.Get("X-Telegram-Bot-Api-Secret-Token")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+		return errors.New("Telegram secret token does not match")
+	}
+	return nil
+}
+
+// telegramUpdate is the part of a Telegram update that is used for inline
+// queries
+type telegramUpdate struct {
+	UpdateID    int64 `json:"update_id"`
+	InlineQuery *struct {
+		ID     string `json:"id"`
+		Query  string `json:"query"`
+		Offset string `json:"offset"`
+	} `json:"inline_query"`
+}
+
+// telegramResult is an InlineQueryResultGif for animated gifs or an
+// InlineQueryResultPhoto for other images
+type telegramResult struct {
+	Type         string `json:"type"`
+	ID           string `json:"id"`
+	GifURL       string `json:"gif_url,omitempty"`
+	GifWidth     int    `json:"gif_width,omitempty"`
+	GifHeight    int    `json:"gif_height,omitempty"`
+	GifDuration  int    `json:"gif_duration,omitempty"`
+	PhotoURL     string `json:"photo_url,omitempty"`
+	PhotoWidth   int    `json:"photo_width,omitempty"`
+	PhotoHeight  int    `json:"photo_height,omitempty"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	Title        string `json:"title"`
+}
+
+// telegramInlineAnswer is the body of an answerInlineQuery call
+type telegramInlineAnswer struct {
+	InlineQueryID string           `json:"inline_query_id"`
+	Results       []telegramResult `json:"results"`
+	CacheTime     int              `json:"cache_time"`
+	NextOffset    string           `json:"next_offset"`
+}
+
+// telegramMimeType returns the mime type of a post image, using the indexed
+// image metadata if it is available
+func telegramMimeType(post tumblr.Post) string {
+	if post.Meta != nil && post.Meta.MimeType != "" {
+		return post.Meta.MimeType
+	}
+	return mime.TypeByExtension(strings.ToLower(path.Ext(post.Image)))
+}
+
+// telegramResults maps posts to inline query results. Telegram only accepts
+// gifs as gif results, so other images are sent as photos.
+func telegramResults(posts []tumblr.Post) []telegramResult {
+	results := []telegramResult{}
+	for _, post := range posts {
+		result := telegramResult{
+			ID:           strconv.FormatInt(post.ID, 10),
+			ThumbnailURL: post.Image,
+			Title:        post.Title,
+		}
+		if telegramMimeType(post) == "image/gif" {
+			result.Type = "gif"
+			result.GifURL = post.Image
+			if post.Meta != nil {
+				result.GifWidth = post.Meta.Width
+				result.GifHeight = post.Meta.Height
+				result.GifDuration = (post.Meta.Duration + 999) / 1000
+			}
+		} else {
+			result.Type = "photo"
+			result.PhotoURL = post.Image
+			if post.Meta != nil {
+				result.PhotoWidth = post.Meta.Width
+				result.PhotoHeight = post.Meta.Height
+			}
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// telegramAnswer returns the answer to an inline query with a page of
+// search results starting at the offset of the query. Empty queries return
+// random posts so they are not paginated.
+func telegramAnswer(board *tumblr.Board, id, query, offsetString string) telegramInlineAnswer {
+	offset, err := strconv.Atoi(offsetString)
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	page, total := board.Search(query, tumblr.ImageFilter{}, offset, maxResults)
+	answer := telegramInlineAnswer{
+		InlineQueryID: id,
+		Results:       telegramResults(page.Posts),
+		CacheTime:     telegramCacheTime,
+	}
+	if query != "" && offset+len(page.Posts) < total {
+		answer.NextOffset = strconv.Itoa(offset + len(page.Posts))
+	}
+	return answer
+}
+
+// callTelegram calls a Bot API method, which is sent to TELEGRAM_API_URL if
+// set so that a stand-in server can be used
+func callTelegram(method string, body interface{}) error {
+	apiURL := os.Getenv("TELEGRAM_API_URL")
+	if apiURL == "" {
+		apiURL = telegramDefaultAPIURL
+	}
+	endpoint := fmt.Sprintf("%s/bot%s/%s", strings.TrimRight(apiURL, "/"), os.Getenv("TELEGRAM_BOT_TOKEN"), method)
+	data, _ := json.Marshal(body)
+	client := http.Client{Timeout: telegramTimeout}
+	response, err := client.Post(endpoint, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return errors.Wrapf(err, "Cannot call Telegram %s", method)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return errors.Errorf("Telegram %s returned status %d", method, response.StatusCode)
+	}
+	return nil
+}
+
+// telegramHandler answers Telegram inline queries with matching posts
+func telegramHandler(w http.ResponseWriter, r *http.Request, d handlerDeps) {
+	err := verifyTelegramRequest(r.Header, os.Getenv("TELEGRAM_SECRET_TOKEN"))
+	if err != nil {
+		d.logger.Warn(err)
+		rollbarRequestError(rollbar.WARN, r, err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	update := telegramUpdate{}
+	if err == nil {
+		err = json.Unmarshal(body, &update)
+	}
+	if err != nil {
+		err = errors.Wrap(err, "Cannot parse Telegram update")
+		d.logger.Warn(err)
+		rollbarRequestError(rollbar.WARN, r, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if update.InlineQuery == nil {
+		return
+	}
+	query := update.InlineQuery
+	answer := telegramAnswer(d.board, query.ID, query.Query, query.Offset)
+	err = callTelegram("answerInlineQuery", answer)
+	if err != nil {
+		d.logger.Error(err)
+		rollbarRequestError(rollbar.ERR, r, err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+}
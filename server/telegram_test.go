@@ -0,0 +1,182 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/albertyw/reaction-pics/tumblr"
+	"github.com/stretchr/testify/assert"
+)
+
+// telegramStandIn is a local stand-in for the Bot API that records calls
+type telegramStandIn struct {
+	server  *httptest.Server
+	paths   []string
+	answers []telegramInlineAnswer
+}
+
+func newTelegramStandIn(t *testing.T, status int) (*telegramStandIn, func()) {
+	standIn := &telegramStandIn{}
+	standIn.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		answer := telegramInlineAnswer{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&answer))
+		standIn.paths = append(standIn.paths, r.URL.Path)
+		standIn.answers = append(standIn.answers, answer)
+		w.WriteHeader(status)
+	}))
+	env := map[string]string{
+		"TELEGRAM_API_URL":      standIn.server.URL,
+		"TELEGRAM_BOT_TOKEN":    "123:abc",
+		"TELEGRAM_SECRET_TOKEN": "secret",
+	}
+	orig := map[string]string{}
+	for key, value := range env {
+		orig[key] = os.Getenv(key)
+		os.Setenv(key, value)
+	}
+	return standIn, func() {
+		standIn.server.Close()
+		for key, value := range orig {
+			os.Setenv(key, value)
+		}
+	}
+}
+
+func telegramRequest(t *testing.T, body []byte, secret string) *http.Request {
+	request, err := http.NewRequest("POST", "/integrations/telegram", bytes.NewReader(body))
+	assert.NoError(t, err)
+	request.Header.Set("X-Telegram-Bot-Api-Secret-Token", secret)
+	return request
+}
+
+func TestVerifyTelegramRequest(t *testing.T) {
+	header := http.Header{}
+	header.Set("X-Telegram-Bot-Api-Secret-Token", "secret")
+	assert.NoError(t, verifyTelegramRequest(header, "secret"))
+	assert.Error(t, verifyTelegramRequest(header, "other"))
+	assert.Error(t, verifyTelegramRequest(header, ""))
+	assert.Error(t, verifyTelegramRequest(http.Header{}, "secret"))
+}
+
+func TestTelegramResults(t *testing.T) {
+	posts := []tumblr.Post{
+		{ID: 1, Title: "a", Image: "https://example.com/1.gif", Meta: &tumblr.ImageMeta{Width: 500, Height: 280, Duration: 1200, MimeType: "image/gif"}},
+		{ID: 2, Title: "b", Image: "https://example.com/2.gif"},
+		{ID: 3, Title: "c", Image: "https://example.com/3.gif", Meta: &tumblr.ImageMeta{Width: 400, Height: 300, MimeType: "image/png"}},
+		{ID: 4, Title: "d", Image: "https://example.com/4.JPG"},
+	}
+	results := telegramResults(posts)
+	assert.Equal(t, len(results), 4)
+	assert.Equal(t, results[0], telegramResult{
+		Type:         "gif",
+		ID:           "1",
+		GifURL:       "https://example.com/1.gif",
+		GifWidth:     500,
+		GifHeight:    280,
+		GifDuration:  2,
+		ThumbnailURL: "https://example.com/1.gif",
+		Title:        "a",
+	})
+	assert.Equal(t, results[1].Type, "gif")
+	assert.Equal(t, results[1].GifWidth, 0)
+	assert.Equal(t, results[2], telegramResult{
+		Type:         "photo",
+		ID:           "3",
+		PhotoURL:     "https://example.com/3.gif",
+		PhotoWidth:   400,
+		PhotoHeight:  300,
+		ThumbnailURL: "https://example.com/3.gif",
+		Title:        "c",
+	})
+	assert.Equal(t, results[3].Type, "photo")
+	assert.Equal(t, results[3].PhotoURL, "https://example.com/4.JPG")
+	assert.Equal(t, results[3].GifURL, "")
+}
+
+func TestTelegramAnswerPagination(t *testing.T) {
+	posts := []tumblr.Post{}
+	for i := 1; i <= maxResults+5; i++ {
+		posts = append(posts, tumblr.Post{ID: int64(i), Title: "Outage", Image: "https://example.com/a.gif"})
+	}
+	board := tumblr.NewBoard(posts)
+
+	answer := telegramAnswer(&board, "1", "outage", "")
+	assert.Equal(t, len(answer.Results), maxResults)
+	assert.Equal(t, answer.NextOffset, "20")
+	answer = telegramAnswer(&board, "1", "outage", answer.NextOffset)
+	assert.Equal(t, len(answer.Results), 5)
+	assert.Equal(t, answer.NextOffset, "")
+	answer = telegramAnswer(&board, "1", "", "")
+	assert.Equal(t, answer.NextOffset, "")
+}
+
+func (s *HandlerTestSuite) TestTelegramHandler() {
+	s.addPosts(chatTestPosts)
+	standIn, reset := newTelegramStandIn(s.T(), http.StatusOK)
+	defer reset()
+	body, err := ioutil.ReadFile("testdata/telegram_inline_query.json")
+	assert.NoError(s.T(), err)
+
+	response := httptest.NewRecorder()
+	telegramHandler(response, telegramRequest(s.T(), body, "secret"), s.deps)
+	assert.Equal(s.T(), response.Code, 200)
+	assert.Equal(s.T(), standIn.paths, []string{"/bot123:abc/answerInlineQuery"})
+	answer := standIn.answers[0]
+	assert.Equal(s.T(), answer.InlineQueryID, "134567890097")
+	assert.Equal(s.T(), len(answer.Results), 2)
+	assert.Equal(s.T(), answer.Results[0].Title, "Outage one")
+	assert.Equal(s.T(), answer.Results[0].ThumbnailURL, "https://example.com/1.gif")
+}
+
+func (s *HandlerTestSuite) TestTelegramHandlerUnauthorized() {
+	s.addPosts(chatTestPosts)
+	standIn, reset := newTelegramStandIn(s.T(), http.StatusOK)
+	defer reset()
+
+	response := httptest.NewRecorder()
+	telegramHandler(response, telegramRequest(s.T(), []byte(`{}`), "wrong"), s.deps)
+	assert.Equal(s.T(), response.Code, 401)
+	assert.Equal(s.T(), len(standIn.paths), 0)
+}
+
+func (s *HandlerTestSuite) TestTelegramHandlerTooLarge() {
+	s.addPosts(chatTestPosts)
+	standIn, reset := newTelegramStandIn(s.T(), http.StatusOK)
+	defer reset()
+	body := []byte(`{"update_id":1,"padding":"` + strings.Repeat("a", maxRequestBodySize) + `"}`)
+
+	response := httptest.NewRecorder()
+	telegramHandler(response, telegramRequest(s.T(), body, "secret"), s.deps)
+	assert.Equal(s.T(), response.Code, 400)
+	assert.Equal(s.T(), len(standIn.paths), 0)
+}
+
+func (s *HandlerTestSuite) TestTelegramHandlerOtherUpdate() {
+	s.addPosts(chatTestPosts)
+	standIn, reset := newTelegramStandIn(s.T(), http.StatusOK)
+	defer reset()
+
+	response := httptest.NewRecorder()
+	body := []byte(`{"update_id":1,"message":{"text":"hi"}}`)
+	telegramHandler(response, telegramRequest(s.T(), body, "secret"), s.deps)
+	assert.Equal(s.T(), response.Code, 200)
+	assert.Equal(s.T(), len(standIn.paths), 0)
+}
+
+func (s *HandlerTestSuite) TestTelegramHandlerAPIError() {
+	s.addPosts(chatTestPosts)
+	_, reset := newTelegramStandIn(s.T(), http.StatusBadRequest)
+	defer reset()
+	body, err := ioutil.ReadFile("testdata/telegram_inline_query.json")
+	assert.NoError(s.T(), err)
+
+	response := httptest.NewRecorder()
+	telegramHandler(response, telegramRequest(s.T(), body, "secret"), s.deps)
+	assert.Equal(s.T(), response.Code, 502)
+}
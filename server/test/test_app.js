@@ -14,9 +14,13 @@ context('Varsnap', function() {
   this.timeout(30 * 1000);
   beforeEach(function() {
     // Set up html DOM
+    const search = document.createElement('form');
+    search.setAttribute('id', 'search');
+    document.body.appendChild(search);
+
     const query = document.createElement('input');
     query.setAttribute('id', 'query');
-    document.body.appendChild(query);
+    search.appendChild(query);
 
     const results = document.createElement('div');
     results.setAttribute('id', 'results');
@@ -26,6 +30,17 @@ context('Varsnap', function() {
     data.setAttribute('id', 'data');
     document.body.appendChild(data);
 
+    const resultTemplate = document.createElement('template');
+    resultTemplate.setAttribute('id', 'resultTemplate');
+    resultTemplate.innerHTML = '<div class="result">' +
+      '<h2><a class="result-title" href=""></a></h2>' +
+      '<p class="result-image" hidden><img class="result-img" loading="lazy" /></p>' +
+      '<div class="result-likes" hidden>' +
+      '<p><a href="#" class="btn btn-success disabled"><span class="result-like-count">0</span></a></p>' +
+      '<p><a class="result-original" href="">Original</a></p>' +
+      '</div></div>';
+    document.body.appendChild(resultTemplate);
+
     const indexStat = document.createElement('div');
     indexStat.setAttribute('id', 'indexStat');
     document.body.appendChild(indexStat);
@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/albertyw/reaction-pics/linkcheck"
 	"github.com/albertyw/reaction-pics/tumblr"
 	"github.com/newrelic/go-agent/v3/newrelic"
 	"github.com/rollbar/rollbar-go"
@@ -25,18 +26,6 @@ func appCacheString(logger *zap.SugaredLogger) string {
 	return cacheString
 }
 
-// logURL is a closure that logs (to stdout) the url and query of requests
-func logURL(
-	targetFunc func(http.ResponseWriter, *http.Request),
-	logger *zap.SugaredLogger,
-) func(http.ResponseWriter, *http.Request) {
-	return func(w http.ResponseWriter, r *http.Request) {
-		url := r.URL.String()
-		logger.Info(url)
-		targetFunc(w, r)
-	}
-}
-
 // rewriteFS wraps a static file handler so to rewrite to the static directory
 // and the root path is rewritten to index.htm
 func rewriteFS(targetFunc func(http.ResponseWriter, *http.Request),
@@ -55,6 +44,10 @@ type handlerDeps struct {
 	logger         *zap.SugaredLogger
 	board          *tumblr.Board
 	appCacheString string
+	linkChecker    *linkcheck.Checker
+	sitemaps       *sitemapCache
+	searches       *searchCache
+	metrics        *metrics
 }
 
 // handlerGenerator returns a struct that can generate wrapped http handler functions
@@ -62,27 +55,53 @@ type handlerGenerator struct {
 	newrelicApp *newrelic.Application
 	logger      *zap.SugaredLogger
 	deps        handlerDeps
+	cors        corsConfig
+	limiter     *rateLimiter
 }
 
-// newHandlerGenerator returns a new handlerGenerator
-func newHandlerGenerator(board *tumblr.Board, newrelicApp *newrelic.Application, logger *zap.SugaredLogger) handlerGenerator {
+// newHandlerGenerator returns a new handlerGenerator, or an error if the CORS
+// or rate limit config cannot be loaded
+func newHandlerGenerator(board *tumblr.Board, newrelicApp *newrelic.Application, logger *zap.SugaredLogger) (handlerGenerator, error) {
 	deps := handlerDeps{
 		logger:         logger,
 		board:          board,
 		appCacheString: appCacheString(logger),
+		sitemaps:       newSitemapCache(),
+		searches:       newSearchCache(searchCacheSize()),
+	}
+	deps.metrics = newMetrics(board, deps.searches)
+	cors, err := loadCORSConfig()
+	if err != nil {
+		return handlerGenerator{}, err
+	}
+	limiter, err := loadRateLimiter()
+	if err != nil {
+		return handlerGenerator{}, err
 	}
 	return handlerGenerator{
 		newrelicApp: newrelicApp,
 		logger:      logger,
 		deps:        deps,
-	}
+		cors:        cors,
+		limiter:     limiter,
+	}, nil
 }
 
 // newHandlerFunc returns a http handler function
 func (g handlerGenerator) newHandler(pattern string, handlerFunc handlerWithDeps,
 ) (string, http.Handler) {
 	f := func(w http.ResponseWriter, r *http.Request) {
-		handlerFunc(w, r, g.deps)
+		d := g.deps
+		d.logger = d.logger.With("requestID", requestID(r))
+		if g.cors.handle(w, r, pattern) {
+			return
+		}
+		allowed, retryAfter := g.limiter.allow(r, pattern)
+		if !allowed {
+			rateLimitedHandler(w, r, d, retryAfter)
+			return
+		}
+		handlerFunc(w, r, d)
 	}
-	return newrelic.WrapHandle(g.newrelicApp, pattern, http.HandlerFunc(logURL(f, g.logger)))
+	return newrelic.WrapHandle(g.newrelicApp, pattern, g.accessLog(pattern, f))
 }
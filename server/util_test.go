@@ -21,7 +21,8 @@ func TestNewHandlerGenerator(t *testing.T) {
 	n := newrelic.Application{}
 	l := zap.NewNop().Sugar()
 	s := appCacheString(l)
-	generator := newHandlerGenerator(&b, &n, l)
+	generator, err := newHandlerGenerator(&b, &n, l)
+	assert.NoError(t, err)
 	assert.Equal(t, generator.newrelicApp, &n)
 	assert.Equal(t, generator.logger, l)
 	assert.Equal(t, generator.deps.logger, l)
@@ -0,0 +1,343 @@
+package tumblr
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"html"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const archivePostsFile = "posts.xml"
+
+var htmlTagRegexp = regexp.MustCompile(`<[^>]*>`)
+
+// archivePost is a post in the posts.xml file of a tumblr export archive
+type archivePost struct {
+	ID            int64    `xml:"id,attr"`
+	URL           string   `xml:"url,attr"`
+	URLWithSlug   string   `xml:"url-with-slug,attr"`
+	Type          string   `xml:"type,attr"`
+	UnixTimestamp int64    `xml:"unix-timestamp,attr"`
+	NoteCount     int64    `xml:"note-count,attr"`
+	RegularTitle  string   `xml:"regular-title"`
+	PhotoCaption  string   `xml:"photo-caption"`
+	PhotoURLs     []string `xml:"photo-url"`
+	Tags          []string `xml:"tag"`
+}
+
+type archivePosts struct {
+	Posts []archivePost `xml:"posts>post"`
+}
+
+// title returns the plain text title of an archived post
+func (a archivePost) title() string {
+	title := a.RegularTitle
+	if title == "" {
+		title = a.PhotoCaption
+	}
+	title = htmlTagRegexp.ReplaceAllString(title, " ")
+	title = html.UnescapeString(title)
+	return strings.Join(strings.Fields(title), " ")
+}
+
+// toPost converts an archived post into a Post
+func (a archivePost) toPost() Post {
+	url := a.URLWithSlug
+	if url == "" {
+		url = a.URL
+	}
+	image := ""
+	if len(a.PhotoURLs) > 0 {
+		image = a.PhotoURLs[0]
+	}
+	return Post{
+		ID:        a.ID,
+		Title:     a.title(),
+		URL:       url,
+		Image:     image,
+		Likes:     a.NoteCount,
+		Tags:      a.Tags,
+		Timestamp: a.UnixTimestamp,
+	}
+}
+
+// archive is a tumblr archive that is either a directory or a zip file
+type archive interface {
+	Open(name string) (io.ReadCloser, error)
+	Files() []string
+	Close() error
+}
+
+type dirArchive struct {
+	dir   string
+	files []string
+}
+
+func (a dirArchive) Open(name string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(a.dir, filepath.FromSlash(name)))
+}
+
+func (a dirArchive) Files() []string { return a.files }
+
+func (a dirArchive) Close() error { return nil }
+
+type zipArchive struct {
+	files  []*zip.File
+	closer io.Closer
+}
+
+func (a zipArchive) Open(name string) (io.ReadCloser, error) {
+	for _, file := range a.files {
+		if file.Name == name {
+			return file.Open()
+		}
+	}
+	return nil, os.ErrNotExist
+}
+
+func (a zipArchive) Files() []string {
+	files := []string{}
+	for _, file := range a.files {
+		if !file.FileInfo().IsDir() {
+			files = append(files, file.Name)
+		}
+	}
+	return files
+}
+
+func (a zipArchive) Close() error {
+	if a.closer == nil {
+		return nil
+	}
+	return a.closer.Close()
+}
+
+// openArchive opens a tumblr export from either an unzipped directory or a zip file
+func openArchive(archivePath string) (archive, error) {
+	info, err := os.Stat(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		reader, err := zip.OpenReader(archivePath)
+		if err != nil {
+			return nil, errors.Wrap(err, "Cannot open archive")
+		}
+		return zipArchive{files: reader.File, closer: reader}, nil
+	}
+	files := []string{}
+	err = filepath.Walk(archivePath, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(archivePath, p)
+		files = append(files, filepath.ToSlash(rel))
+		return err
+	})
+	return dirArchive{dir: archivePath, files: files}, err
+}
+
+// openNestedArchive opens a zip file inside an archive
+func openNestedArchive(a archive, name string) (archive, error) {
+	file, err := a.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Cannot open %s", name)
+	}
+	return zipArchive{files: reader.File}, nil
+}
+
+// findArchiveFile returns the path of the first file in an archive with a
+// name, or "" if there is none
+func findArchiveFile(a archive, name string) string {
+	for _, file := range a.Files() {
+		if path.Base(file) == name {
+			return file
+		}
+	}
+	return ""
+}
+
+// mediaFiles returns the media files of an archive keyed by post id.  Media
+// are named after their post, either as <id>.<ext> or <id>_<n>.<ext>.
+func mediaFiles(a archive) map[int64]string {
+	media := map[int64]string{}
+	for _, file := range a.Files() {
+		dir, name := path.Split(file)
+		if path.Base(dir) != "media" {
+			continue
+		}
+		base := strings.TrimSuffix(name, path.Ext(name))
+		base = strings.SplitN(base, "_", 2)[0]
+		id, err := strconv.ParseInt(base, 10, 64)
+		if err != nil {
+			continue
+		}
+		if existing, ok := media[id]; !ok || file < existing {
+			media[id] = file
+		}
+	}
+	return media
+}
+
+// ReadArchive reads the posts of a tumblr archive, which is either the blog
+// export that tumblr currently offers, with a posts.zip of html pages and a
+// media directory, or a legacy backup with a posts.xml in the v1 API format.
+// Either can be a zip file or an unzipped directory.  The image of each post
+// with archived media points to where CopyArchiveMedia copies the media in
+// the image store, which for legacy posts with photo urls is only done if
+// localMedia is set.  Posts without an image are skipped.
+func ReadArchive(archivePath string, localMedia bool) ([]Post, error) {
+	a, err := openArchive(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer a.Close()
+	var archived []Post
+	if file := findArchiveFile(a, archivePostsFile); file != "" {
+		archived, err = readArchiveXML(a, file)
+	} else if file := findArchiveFile(a, exportPostsZip); file != "" {
+		var postsArchive archive
+		postsArchive, err = openNestedArchive(a, file)
+		if err == nil {
+			archived, err = readExportPosts(postsArchive)
+		}
+	} else if findArchiveFile(a, exportPostsIndex) != "" {
+		archived, err = readExportPosts(a)
+	} else {
+		err = errors.Errorf("Cannot find %s or %s in archive", exportPostsZip, archivePostsFile)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	media := mediaFiles(a)
+	posts := []Post{}
+	for _, post := range archived {
+		if mediaFile, ok := media[post.ID]; ok && (localMedia || post.Image == "") {
+			post.Image = ImageURL(path.Base(mediaFile))
+		}
+		if post.Image == "" {
+			continue
+		}
+		posts = append(posts, post)
+	}
+	return posts, nil
+}
+
+// readArchiveXML reads the posts of a legacy posts.xml backup
+func readArchiveXML(a archive, postsFile string) ([]Post, error) {
+	reader, err := a.Open(postsFile)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	archived := archivePosts{}
+	err = xml.NewDecoder(reader).Decode(&archived)
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot parse posts")
+	}
+	posts := []Post{}
+	for _, archivedPost := range archived.Posts {
+		posts = append(posts, archivedPost.toPost())
+	}
+	return posts, nil
+}
+
+// CopyArchiveMedia copies the archived media of posts read by ReadArchive
+// into imageDir.  Posts whose image is not their archived media are skipped.
+func CopyArchiveMedia(archivePath, imageDir string, posts []Post) error {
+	a, err := openArchive(archivePath)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+	media := mediaFiles(a)
+	for _, post := range posts {
+		mediaFile, ok := media[post.ID]
+		if !ok || post.Image != ImageURL(path.Base(mediaFile)) {
+			continue
+		}
+		err = copyMedia(a, mediaFile, imageDir)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyMedia copies a media file from an archive into imageDir.  Media that
+// is already in imageDir is not overwritten.
+func copyMedia(a archive, mediaFile, imageDir string) error {
+	source, err := a.Open(mediaFile)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	destination, err := os.OpenFile(filepath.Join(imageDir, path.Base(mediaFile)), flags, 0644)
+	if os.IsExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "Cannot copy media")
+	}
+	_, err = io.Copy(destination, source)
+	if closeErr := destination.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// MergePosts merges posts into existing posts, deduplicating by ID.  Existing
+// posts keep their title, url, and image but gain tags and timestamps that
+// they are missing.  It returns the merged posts and the number of added and
+// updated posts.
+func MergePosts(existing, posts []Post) (merged []Post, added, updated int) {
+	merged = make([]Post, len(existing))
+	copy(merged, existing)
+	index := map[int64]int{}
+	for i, post := range merged {
+		index[post.ID] = i
+	}
+	for _, post := range posts {
+		i, ok := index[post.ID]
+		if !ok {
+			index[post.ID] = len(merged)
+			merged = append(merged, post)
+			added++
+			continue
+		}
+		changed := false
+		if len(merged[i].Tags) == 0 && len(post.Tags) > 0 {
+			merged[i].Tags = post.Tags
+			changed = true
+		}
+		if merged[i].Timestamp == 0 && post.Timestamp != 0 {
+			merged[i].Timestamp = post.Timestamp
+			changed = true
+		}
+		if changed {
+			updated++
+		}
+	}
+	return merged, added, updated
+}
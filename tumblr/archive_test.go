@@ -0,0 +1,189 @@
+package tumblr
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testArchivePosts = `<?xml version="1.0" encoding="UTF-8"?>
+<tumblr version="1.0">
+  <posts>
+    <post id="1234" url="http://example.com/post/1234" url-with-slug="http://example.com/post/1234/deploying" type="photo" unix-timestamp="1380000000" note-count="42">
+      <photo-caption>&lt;p&gt;Deploying on &lt;b&gt;Friday&lt;/b&gt; &amp;amp; hoping&lt;/p&gt;</photo-caption>
+      <photo-url max-width="1280">https://example.com/1280.gif</photo-url>
+      <photo-url max-width="500">https://example.com/500.gif</photo-url>
+      <tag>deploy</tag>
+      <tag>friday</tag>
+    </post>
+    <post id="5678" url="http://example.com/post/5678" type="regular" unix-timestamp="1390000000" note-count="7">
+      <regular-title>Rolling back</regular-title>
+    </post>
+    <post id="9999" url="http://example.com/post/9999" type="regular">
+      <regular-title>No image</regular-title>
+    </post>
+  </posts>
+</tumblr>`
+
+func writeTestArchive(t *testing.T, dir string) {
+	err := os.MkdirAll(filepath.Join(dir, "media"), 0755)
+	assert.NoError(t, err)
+	err = ioutil.WriteFile(filepath.Join(dir, "posts.xml"), []byte(testArchivePosts), 0644)
+	assert.NoError(t, err)
+	err = ioutil.WriteFile(filepath.Join(dir, "media", "5678_0.gif"), []byte("gif"), 0644)
+	assert.NoError(t, err)
+}
+
+func TestReadArchiveDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	archiveDir := filepath.Join(dir, "archive")
+	imageDir := filepath.Join(dir, "images")
+	writeTestArchive(t, archiveDir)
+	assert.NoError(t, os.Mkdir(imageDir, 0755))
+
+	posts, err := ReadArchive(archiveDir, true)
+	assert.NoError(t, err)
+	assert.Equal(t, len(posts), 2)
+	assert.Equal(t, posts[0].ID, int64(1234))
+	assert.Equal(t, posts[0].Title, "Deploying on Friday & hoping")
+	assert.Equal(t, posts[0].URL, "http://example.com/post/1234/deploying")
+	assert.Equal(t, posts[0].Image, "https://example.com/1280.gif")
+	assert.Equal(t, posts[0].Likes, int64(42))
+	assert.Equal(t, posts[0].Tags, []string{"deploy", "friday"})
+	assert.Equal(t, posts[0].Timestamp, int64(1380000000))
+
+	assert.Equal(t, posts[1].Title, "Rolling back")
+	assert.Equal(t, posts[1].Image, imageRootPath+"5678_0.gif")
+	files, err := ioutil.ReadDir(imageDir)
+	assert.NoError(t, err)
+	assert.Equal(t, len(files), 0)
+
+	err = CopyArchiveMedia(archiveDir, imageDir, posts)
+	assert.NoError(t, err)
+	data, err := ioutil.ReadFile(filepath.Join(imageDir, "5678_0.gif"))
+	assert.NoError(t, err)
+	assert.Equal(t, string(data), "gif")
+}
+
+func TestReadArchiveZip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	zipPath := filepath.Join(dir, "archive.zip")
+	file, err := os.Create(zipPath)
+	assert.NoError(t, err)
+	writer := zip.NewWriter(file)
+	for name, data := range map[string]string{
+		"blog/posts.xml":         testArchivePosts,
+		"blog/media/5678_0.gif":  "gif",
+		"blog/media/5678_1.gif":  "gif2",
+		"blog/media/unknown.gif": "gif",
+	} {
+		w, err := writer.Create(name)
+		assert.NoError(t, err)
+		_, err = w.Write([]byte(data))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, writer.Close())
+	assert.NoError(t, file.Close())
+
+	posts, err := ReadArchive(zipPath, true)
+	assert.NoError(t, err)
+	assert.Equal(t, len(posts), 2)
+	assert.Equal(t, posts[1].Image, imageRootPath+"5678_0.gif")
+
+	err = CopyArchiveMedia(zipPath, dir, posts)
+	assert.NoError(t, err)
+	data, err := ioutil.ReadFile(filepath.Join(dir, "5678_0.gif"))
+	assert.NoError(t, err)
+	assert.Equal(t, string(data), "gif")
+}
+
+func TestReadArchiveExport(t *testing.T) {
+	exportPath := filepath.Join("testdata", "export.zip")
+	posts, err := ReadArchive(exportPath, false)
+	assert.NoError(t, err)
+	assert.Equal(t, len(posts), 2)
+	assert.Equal(t, posts[0].ID, int64(629784395538309120))
+	assert.Equal(t, posts[0].Title, "When the build finally passes & nobody knows why")
+	assert.Equal(t, posts[0].URL, "")
+	assert.Equal(t, posts[0].Image, imageRootPath+"629784395538309120.gif")
+	assert.Equal(t, posts[0].Tags, []string{"deploy", "ci"})
+	assert.Equal(t, posts[0].Timestamp, int64(1380000000))
+	assert.Equal(t, posts[1].ID, int64(629784698765432109))
+	assert.Equal(t, posts[1].Title, "")
+	assert.Equal(t, posts[1].Image, imageRootPath+"629784698765432109_0.png")
+	assert.Equal(t, posts[1].Timestamp, int64(1386074700))
+
+	dir, err := ioutil.TempDir("", "archive")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	err = CopyArchiveMedia(exportPath, dir, posts)
+	assert.NoError(t, err)
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, len(files), 2)
+	data, err := ioutil.ReadFile(filepath.Join(dir, "629784698765432109_0.png"))
+	assert.NoError(t, err)
+	assert.Equal(t, string(data), "png")
+}
+
+func TestCopyArchiveMediaExisting(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	archiveDir := filepath.Join(dir, "archive")
+	imageDir := filepath.Join(dir, "images")
+	writeTestArchive(t, archiveDir)
+	assert.NoError(t, os.Mkdir(imageDir, 0755))
+	err = ioutil.WriteFile(filepath.Join(imageDir, "5678_0.gif"), []byte("existing"), 0644)
+	assert.NoError(t, err)
+
+	posts, err := ReadArchive(archiveDir, true)
+	assert.NoError(t, err)
+	err = CopyArchiveMedia(archiveDir, imageDir, posts)
+	assert.NoError(t, err)
+	data, err := ioutil.ReadFile(filepath.Join(imageDir, "5678_0.gif"))
+	assert.NoError(t, err)
+	assert.Equal(t, string(data), "existing")
+}
+
+func TestReadArchiveMissing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = ReadArchive(dir, false)
+	assert.Error(t, err)
+	_, err = ReadArchive(filepath.Join(dir, "missing"), false)
+	assert.Error(t, err)
+}
+
+func TestMergePosts(t *testing.T) {
+	existing := []Post{
+		{ID: 1, Title: "title1", Image: "image1"},
+		{ID: 2, Title: "title2", Image: "image2", Tags: []string{"tag"}, Timestamp: 10},
+	}
+	posts := []Post{
+		{ID: 1, Title: "new title", Image: "new image", Tags: []string{"a", "b"}, Timestamp: 5},
+		{ID: 2, Title: "new title", Tags: []string{"c"}, Timestamp: 20},
+		{ID: 3, Title: "title3"},
+	}
+	merged, added, updated := MergePosts(existing, posts)
+	assert.Equal(t, added, 1)
+	assert.Equal(t, updated, 1)
+	assert.Equal(t, len(merged), 3)
+	assert.Equal(t, merged[0].Title, "title1")
+	assert.Equal(t, merged[0].Image, "image1")
+	assert.Equal(t, merged[0].Tags, []string{"a", "b"})
+	assert.Equal(t, merged[0].Timestamp, int64(5))
+	assert.Equal(t, merged[1].Tags, []string{"tag"})
+	assert.Equal(t, merged[2].ID, int64(3))
+	assert.Equal(t, existing[0].Tags, []string(nil))
+}
@@ -2,7 +2,6 @@ package tumblr
 
 import (
 	"encoding/csv"
-	"fmt"
 	"io"
 	"os"
 	"path/filepath"
@@ -26,6 +25,7 @@ func ReadPostsFromCSV(csvPath string) []Post {
 
 func readCSV(data io.Reader) []Post {
 	reader := csv.NewReader(data)
+	reader.FieldsPerRecord = -1
 	var posts []Post
 	for {
 		row, err := reader.Read()
@@ -38,6 +38,33 @@ func readCSV(data io.Reader) []Post {
 	return posts
 }
 
+// WritePostsToCSV writes a list of posts to a CSV file
+func WritePostsToCSV(csvPath string, posts []Post) error {
+	file, err := os.Create(csvPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return WritePosts(file, posts)
+}
+
+// WritePosts writes a list of posts in CSV format
+func WritePosts(w io.Writer, posts []Post) error {
+	writer := csv.NewWriter(w)
+	for _, post := range posts {
+		if err := writer.Write(PostToCSV(post)); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// SavedPostsPath returns the path of the CSV file that posts are saved to
+func SavedPostsPath() string {
+	return getCSVPath(false)
+}
+
 func getCSVPath(test bool) string {
 	path := prodCSVPath
 	if test {
@@ -48,6 +75,5 @@ func getCSVPath(test bool) string {
 		filename = "."
 	}
 	path = filepath.Join(filepath.Dir(filename), path)
-	fmt.Println(path)
 	return path
 }
@@ -1,6 +1,7 @@
 package tumblr
 
 import (
+	"bytes"
 	"strings"
 	"testing"
 
@@ -22,3 +23,22 @@ func TestReadPercentFromCSV(t *testing.T) {
 	posts := readCSV(strings.NewReader(data))
 	assert.Equal(t, posts[0].Title, "a% b")
 }
+
+func TestReadExtendedCSV(t *testing.T) {
+	data := "1234,title,url,image,123\n5678,title,url,https://example.com/a.gif,1,\"a,b\",1380000000\n"
+	posts := readCSV(strings.NewReader(data))
+	assert.Equal(t, len(posts), 2)
+	assert.Equal(t, posts[0].Tags, []string(nil))
+	assert.Equal(t, posts[1].Image, "https://example.com/a.gif")
+	assert.Equal(t, posts[1].Tags, []string{"a", "b"})
+	assert.Equal(t, posts[1].Timestamp, int64(1380000000))
+}
+
+func TestWriteCSV(t *testing.T) {
+	data := "1234,title,url,image.gif,123\n5678,\"a, b\",url,https://example.com/a.gif,1,\"a,b\",1380000000\n"
+	posts := readCSV(strings.NewReader(data))
+	buf := bytes.Buffer{}
+	err := WritePosts(&buf, posts)
+	assert.NoError(t, err)
+	assert.Equal(t, buf.String(), data)
+}
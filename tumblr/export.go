@@ -0,0 +1,124 @@
+package tumblr
+
+import (
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/html"
+)
+
+const (
+	exportPostsZip   = "posts.zip"
+	exportPostsIndex = "posts_index.html"
+	// exportTimeLayout is the format of post dates in a blog export, after
+	// removing the ordinal suffix of the day
+	exportTimeLayout = "January 2, 2006 3:04pm"
+)
+
+var ordinalRegexp = regexp.MustCompile(`(\d+)(st|nd|rd|th),`)
+
+// exportPost is the content of a post page in a blog export
+type exportPost struct {
+	heading   string
+	caption   string
+	timestamp string
+	tags      []string
+}
+
+// toPost converts a post page into a Post.  Exports do not have the url or
+// notes of posts, or remote urls of their images.
+func (e exportPost) toPost(id int64) Post {
+	title := e.heading
+	if title == "" {
+		title = e.caption
+	}
+	post := Post{
+		ID:    id,
+		Title: strings.Join(strings.Fields(title), " "),
+		Tags:  e.tags,
+	}
+	timestamp := ordinalRegexp.ReplaceAllString(strings.TrimSpace(e.timestamp), "$1,")
+	if t, err := time.Parse(exportTimeLayout, timestamp); err == nil {
+		post.Timestamp = t.Unix()
+	}
+	return post
+}
+
+// hasAttr returns whether an html element has an attribute containing a
+// space separated value
+func hasAttr(node *html.Node, key, value string) bool {
+	for _, attr := range node.Attr {
+		if attr.Key == key && strings.Contains(" "+attr.Val+" ", " "+value+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeText returns the text inside an html node
+func nodeText(node *html.Node) string {
+	if node.Type == html.TextNode {
+		return node.Data
+	}
+	text := ""
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		text += nodeText(child) + " "
+	}
+	return text
+}
+
+// parseExportPost reads the heading, caption, date, and tags of a post page
+func parseExportPost(node *html.Node, post *exportPost) {
+	if node.Type == html.ElementNode {
+		switch {
+		case node.Data == "h1" && post.heading == "":
+			post.heading = nodeText(node)
+			return
+		case hasAttr(node, "class", "caption") && post.caption == "":
+			post.caption = nodeText(node)
+			return
+		case hasAttr(node, "id", "timestamp"):
+			post.timestamp = nodeText(node)
+			return
+		case hasAttr(node, "class", "tag"):
+			post.tags = append(post.tags, strings.TrimSpace(nodeText(node)))
+			return
+		}
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		parseExportPost(child, post)
+	}
+}
+
+// readExportPosts reads the posts of the posts.zip of a blog export, which
+// has a page for each post at html/<id>.html
+func readExportPosts(a archive) ([]Post, error) {
+	posts := []Post{}
+	for _, file := range a.Files() {
+		dir, name := path.Split(file)
+		if path.Base(dir) != "html" || path.Ext(name) != ".html" {
+			continue
+		}
+		id, err := strconv.ParseInt(strings.TrimSuffix(name, ".html"), 10, 64)
+		if err != nil {
+			continue
+		}
+		reader, err := a.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		document, err := html.Parse(reader)
+		reader.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "Cannot parse post %d", id)
+		}
+		page := exportPost{}
+		parseExportPost(document, &page)
+		posts = append(posts, page.toPost(id))
+	}
+	return posts, nil
+}
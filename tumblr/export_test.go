@@ -0,0 +1,18 @@
+package tumblr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportPostToPost(t *testing.T) {
+	post := exportPost{heading: " Release\n notes ", caption: "caption", timestamp: " October 1st, 2013 11:02pm "}.toPost(1)
+	assert.Equal(t, post.ID, int64(1))
+	assert.Equal(t, post.Title, "Release notes")
+	assert.Equal(t, post.Timestamp, int64(1380668520))
+
+	post = exportPost{caption: "caption", timestamp: "yesterday"}.toPost(2)
+	assert.Equal(t, post.Title, "caption")
+	assert.Equal(t, post.Timestamp, int64(0))
+}
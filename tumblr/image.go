@@ -0,0 +1,267 @@
+package tumblr
+
+import (
+	"bytes"
+	"encoding/csv"
+	stderrors "errors"
+	"image"
+	"image/gif"
+	_ "image/jpeg" // register jpeg decoding for image.DecodeConfig
+	_ "image/png"  // register png decoding for image.DecodeConfig
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	prodImageCSVPath = "data/images.csv"
+	testImageCSVPath = "data/images_test.csv"
+
+	// ImageTypeStatic filters for posts with single frame images
+	ImageTypeStatic = "static"
+	// ImageTypeAnimated filters for posts with multiple frame images
+	ImageTypeAnimated = "animated"
+)
+
+// ImageMeta is a description of the image file attached to a post
+type ImageMeta struct {
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	Frames   int    `json:"frames"`
+	Duration int    `json:"duration"`
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+}
+
+// Animated returns whether the image has more than one frame
+func (m ImageMeta) Animated() bool {
+	return m.Frames > 1
+}
+
+// ReadImageMeta decodes image data and describes it.  Duration is the total
+// time in milliseconds it takes to play through all frames of an animation.
+func ReadImageMeta(data []byte) (*ImageMeta, error) {
+	meta := ImageMeta{
+		Size:     int64(len(data)),
+		MimeType: http.DetectContentType(data),
+		Frames:   1,
+	}
+	if meta.MimeType == "image/gif" {
+		g, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return nil, errors.Wrap(err, "Cannot decode gif")
+		}
+		meta.Width = g.Config.Width
+		meta.Height = g.Config.Height
+		meta.Frames = len(g.Image)
+		for _, delay := range g.Delay {
+			meta.Duration += delay * 10
+		}
+		return &meta, nil
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot decode image")
+	}
+	meta.Width = config.Width
+	meta.Height = config.Height
+	return &meta, nil
+}
+
+// ErrNotIndexed is returned for image filters when no images have been
+// indexed into images.csv
+var ErrNotIndexed = errors.New("Cannot filter by image because no images are indexed; run reaction-pics index <image dir>")
+
+// ImageFilter is a set of constraints on the image of a post
+type ImageFilter struct {
+	// Type is one of ImageTypeStatic, ImageTypeAnimated, or empty for any
+	Type string
+	// MaxDuration is the longest allowed animation in milliseconds, or 0 for any
+	MaxDuration int
+}
+
+// Empty returns whether the filter has no constraints
+func (f ImageFilter) Empty() bool {
+	return f.Type == "" && f.MaxDuration == 0
+}
+
+// Validate returns an error if the filter has an unknown image type or a
+// negative maximum duration
+func (f ImageFilter) Validate() error {
+	if f.Type != "" && f.Type != ImageTypeStatic && f.Type != ImageTypeAnimated {
+		return errors.Errorf("Unknown image type %s", f.Type)
+	}
+	if f.MaxDuration < 0 {
+		return errors.Errorf("Invalid max duration %d", f.MaxDuration)
+	}
+	return nil
+}
+
+// Match returns whether image metadata satisfies the filter.  Images that
+// have not been indexed only match an empty filter.
+func (f ImageFilter) Match(m *ImageMeta) bool {
+	if f.Empty() {
+		return true
+	}
+	if m == nil {
+		return false
+	}
+	if f.Type == ImageTypeStatic && m.Animated() {
+		return false
+	}
+	if f.Type == ImageTypeAnimated && !m.Animated() {
+		return false
+	}
+	if f.MaxDuration > 0 && m.Duration > f.MaxDuration {
+		return false
+	}
+	return true
+}
+
+// imageName returns the file name of an image url
+func imageName(imageURL string) string {
+	return path.Base(imageURL)
+}
+
+// IndexImages reads the image of each post from imageDir and returns
+// metadata keyed by image file name.  Images that cannot be read are skipped
+// and every failure is reported in the returned error.
+func IndexImages(posts []Post, imageDir string) (map[string]ImageMeta, error) {
+	metas := map[string]ImageMeta{}
+	indexErrs := []error{}
+	for _, post := range posts {
+		name := imageName(post.Image)
+		if _, ok := metas[name]; ok {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(imageDir, name))
+		if err != nil {
+			indexErrs = append(indexErrs, errors.Wrapf(err, "Cannot read image %s", name))
+			continue
+		}
+		meta, err := ReadImageMeta(data)
+		if err != nil {
+			indexErrs = append(indexErrs, errors.Wrapf(err, "Cannot index image %s", name))
+			continue
+		}
+		metas[name] = *meta
+	}
+	return metas, stderrors.Join(indexErrs...)
+}
+
+// ReadImageMetaFromCSV reads a CSV file of image metadata keyed by image file name
+func ReadImageMetaFromCSV(csvPath string) map[string]ImageMeta {
+	file, err := os.Open(csvPath)
+	if err != nil {
+		return map[string]ImageMeta{}
+	}
+	defer file.Close()
+	return readImageMetaCSV(file)
+}
+
+func readImageMetaCSV(data io.Reader) map[string]ImageMeta {
+	reader := csv.NewReader(data)
+	metas := map[string]ImageMeta{}
+	for {
+		row, err := reader.Read()
+		if err != nil {
+			break
+		}
+		if len(row) < 7 {
+			continue
+		}
+		width, _ := strconv.Atoi(row[1])
+		height, _ := strconv.Atoi(row[2])
+		frames, _ := strconv.Atoi(row[3])
+		duration, _ := strconv.Atoi(row[4])
+		size, _ := strconv.ParseInt(row[5], 10, 64)
+		metas[row[0]] = ImageMeta{
+			Width:    width,
+			Height:   height,
+			Frames:   frames,
+			Duration: duration,
+			Size:     size,
+			MimeType: row[6],
+		}
+	}
+	return metas
+}
+
+// WriteImageMetaToCSV writes image metadata keyed by image file name to a CSV file
+func WriteImageMetaToCSV(csvPath string, metas map[string]ImageMeta) error {
+	file, err := os.Create(csvPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return writeImageMetaCSV(file, metas)
+}
+
+func writeImageMetaCSV(w io.Writer, metas map[string]ImageMeta) error {
+	writer := csv.NewWriter(w)
+	names := make([]string, 0, len(metas))
+	for name := range metas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		meta := metas[name]
+		row := []string{
+			name,
+			strconv.Itoa(meta.Width),
+			strconv.Itoa(meta.Height),
+			strconv.Itoa(meta.Frames),
+			strconv.Itoa(meta.Duration),
+			strconv.FormatInt(meta.Size, 10),
+			meta.MimeType,
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// IndexImagesToCSV indexes the images of all saved posts from imageDir and
+// saves the metadata next to the post data
+func IndexImagesToCSV(imageDir string) error {
+	posts := ReadPostsFromCSV(getCSVPath(false))
+	metas, indexErr := IndexImages(posts, imageDir)
+	err := WriteImageMetaToCSV(getImageCSVPath(false), metas)
+	if err != nil {
+		return err
+	}
+	return indexErr
+}
+
+func getImageCSVPath(test bool) string {
+	path := prodImageCSVPath
+	if test {
+		path = testImageCSVPath
+	}
+	_, filename, _, ok := runtime.Caller(0)
+	if !ok {
+		filename = "."
+	}
+	return filepath.Join(filepath.Dir(filename), path)
+}
+
+// attachImageMeta sets the image metadata of posts whose image has been indexed
+func attachImageMeta(posts []Post, metas map[string]ImageMeta) {
+	for i := range posts {
+		meta, ok := metas[imageName(posts[i].Image)]
+		if !ok {
+			continue
+		}
+		posts[i].Meta = &meta
+	}
+}
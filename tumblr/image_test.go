@@ -0,0 +1,139 @@
+package tumblr
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testGIF(t *testing.T, frames int) []byte {
+	palette := color.Palette{color.Black, color.White}
+	g := gif.GIF{}
+	for i := 0; i < frames; i++ {
+		g.Image = append(g.Image, image.NewPaletted(image.Rect(0, 0, 4, 3), palette))
+		g.Delay = append(g.Delay, 50)
+	}
+	buf := bytes.Buffer{}
+	err := gif.EncodeAll(&buf, &g)
+	assert.NoError(t, err)
+	return buf.Bytes()
+}
+
+func testPNG(t *testing.T) []byte {
+	buf := bytes.Buffer{}
+	err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 7, 5)))
+	assert.NoError(t, err)
+	return buf.Bytes()
+}
+
+func TestReadImageMetaGIF(t *testing.T) {
+	data := testGIF(t, 3)
+	meta, err := ReadImageMeta(data)
+	assert.NoError(t, err)
+	assert.Equal(t, meta.Width, 4)
+	assert.Equal(t, meta.Height, 3)
+	assert.Equal(t, meta.Frames, 3)
+	assert.Equal(t, meta.Duration, 1500)
+	assert.Equal(t, meta.Size, int64(len(data)))
+	assert.Equal(t, meta.MimeType, "image/gif")
+	assert.True(t, meta.Animated())
+}
+
+func TestReadImageMetaPNG(t *testing.T) {
+	meta, err := ReadImageMeta(testPNG(t))
+	assert.NoError(t, err)
+	assert.Equal(t, meta.Width, 7)
+	assert.Equal(t, meta.Height, 5)
+	assert.Equal(t, meta.Frames, 1)
+	assert.Equal(t, meta.Duration, 0)
+	assert.Equal(t, meta.MimeType, "image/png")
+	assert.False(t, meta.Animated())
+}
+
+func TestReadImageMetaCorrupt(t *testing.T) {
+	_, err := ReadImageMeta([]byte("asdf"))
+	assert.Error(t, err)
+}
+
+func TestImageFilterMatch(t *testing.T) {
+	static := &ImageMeta{Frames: 1}
+	short := &ImageMeta{Frames: 10, Duration: 1000}
+	long := &ImageMeta{Frames: 100, Duration: 10000}
+
+	filter := ImageFilter{}
+	assert.True(t, filter.Match(nil))
+	assert.True(t, filter.Match(static))
+
+	filter = ImageFilter{Type: ImageTypeStatic}
+	assert.False(t, filter.Match(nil))
+	assert.True(t, filter.Match(static))
+	assert.False(t, filter.Match(short))
+
+	filter = ImageFilter{Type: ImageTypeAnimated, MaxDuration: 2000}
+	assert.False(t, filter.Match(static))
+	assert.True(t, filter.Match(short))
+	assert.False(t, filter.Match(long))
+}
+
+func TestImageFilterValidate(t *testing.T) {
+	assert.NoError(t, ImageFilter{}.Validate())
+	assert.NoError(t, ImageFilter{Type: ImageTypeAnimated, MaxDuration: 2000}.Validate())
+	assert.Error(t, ImageFilter{Type: "video"}.Validate())
+	assert.Error(t, ImageFilter{Type: ImageTypeStatic, MaxDuration: -1}.Validate())
+}
+
+func TestIndexImages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "images")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	err = ioutil.WriteFile(filepath.Join(dir, "a.gif"), testGIF(t, 2), 0644)
+	assert.NoError(t, err)
+	err = ioutil.WriteFile(filepath.Join(dir, "b.png"), testPNG(t), 0644)
+	assert.NoError(t, err)
+
+	posts := []Post{
+		{ID: 1, Image: imageRootPath + "a.gif"},
+		{ID: 2, Image: imageRootPath + "b.png"},
+		{ID: 3, Image: imageRootPath + "missing.gif"},
+		{ID: 4, Image: imageRootPath + "other.gif"},
+	}
+	metas, err := IndexImages(posts, dir)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "missing.gif")
+	assert.Contains(t, err.Error(), "other.gif")
+	assert.Equal(t, len(metas), 2)
+	assert.Equal(t, metas["a.gif"].Frames, 2)
+	assert.Equal(t, metas["b.png"].MimeType, "image/png")
+}
+
+func TestImageMetaCSV(t *testing.T) {
+	metas := map[string]ImageMeta{
+		"a.gif": {Width: 1, Height: 2, Frames: 3, Duration: 4, Size: 5, MimeType: "image/gif"},
+	}
+	buf := bytes.Buffer{}
+	err := writeImageMetaCSV(&buf, metas)
+	assert.NoError(t, err)
+	assert.Equal(t, buf.String(), "a.gif,1,2,3,4,5,image/gif\n")
+
+	readMetas := readImageMetaCSV(strings.NewReader(buf.String()))
+	assert.Equal(t, readMetas, metas)
+}
+
+func TestReadImageMetaFromCSV(t *testing.T) {
+	metas := ReadImageMetaFromCSV(getImageCSVPath(true))
+	assert.Equal(t, len(metas), 1)
+	assert.Equal(t, metas["abcd.gif"].Width, 500)
+
+	posts := ReadPostsFromCSV(getCSVPath(true))
+	attachImageMeta(posts, metas)
+	assert.Equal(t, posts[0].Meta.Frames, 12)
+}
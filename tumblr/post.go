@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gosimple/slug"
 	"github.com/pkg/errors"
@@ -18,13 +19,23 @@ const (
 	imageRootPath = "https://img.reaction.pics/file/reaction-pics/"
 )
 
+// ImageURL returns the url of an image in the image store
+func ImageURL(name string) string {
+	return imageRootPath + name
+}
+
 // Post is a representation of a single tumblr post
 type Post struct {
-	ID    int64  `json:"id"`
-	Title string `json:"title"`
-	URL   string `json:"url"`
-	Image string `json:"image"`
-	Likes int64  `json:"likes"`
+	ID    int64      `json:"id"`
+	Title string     `json:"title"`
+	URL   string     `json:"url"`
+	Image string     `json:"image"`
+	Likes int64      `json:"likes"`
+	Meta  *ImageMeta `json:"meta,omitempty"`
+	// Tags are the tumblr tags of the post
+	Tags []string `json:"tags,omitempty"`
+	// Timestamp is the unix time the post was published
+	Timestamp int64 `json:"timestamp,omitempty"`
 }
 
 // PostJSON is a representation of Post for creating JSON values
@@ -58,7 +69,10 @@ func CSVToPost(row []string) *Post {
 		id = 0
 	}
 
-	imageURL := imageRootPath + row[3]
+	imageURL := row[3]
+	if !strings.HasPrefix(imageURL, "http://") && !strings.HasPrefix(imageURL, "https://") {
+		imageURL = imageRootPath + imageURL
+	}
 
 	likes, err := strconv.ParseInt(row[4], 10, 64)
 	if err != nil {
@@ -73,38 +87,98 @@ func CSVToPost(row []string) *Post {
 		Image: imageURL,
 		Likes: likes,
 	}
+	if len(row) > 5 && row[5] != "" {
+		post.Tags = strings.Split(row[5], ",")
+	}
+	if len(row) > 6 && row[6] != "" {
+		timestamp, err := strconv.ParseInt(row[6], 10, 64)
+		if err != nil {
+			err = errors.Wrapf(err, "Cannot parse timestamp for %s", row[6])
+			rollbar.Error(rollbar.ERR, err)
+		}
+		post.Timestamp = timestamp
+	}
 	return &post
 }
 
+// PostToCSV converts a Post into a CSV row.  Tags and timestamp columns are
+// only included if the post has them.
+func PostToCSV(p Post) []string {
+	row := []string{
+		strconv.FormatInt(p.ID, 10),
+		p.Title,
+		p.URL,
+		strings.TrimPrefix(p.Image, imageRootPath),
+		strconv.FormatInt(p.Likes, 10),
+	}
+	if len(p.Tags) > 0 || p.Timestamp != 0 {
+		timestamp := ""
+		if p.Timestamp != 0 {
+			timestamp = strconv.FormatInt(p.Timestamp, 10)
+		}
+		row = append(row, strings.Join(p.Tags, ","), timestamp)
+	}
+	return row
+}
+
 // Board is a container for Posts that offers serialization, sorting, and
 // parallelization
 type Board struct {
-	Posts []Post
-	mut   *sync.RWMutex
+	Posts   []Post
+	mut     *sync.RWMutex
+	version int64
+	updated time.Time
+	// loadDuration is how long reading saved posts took
+	loadDuration time.Duration
+	// loaded is closed once saved posts have been read
+	loaded chan struct{}
 }
 
 // InitializeBoard means to create a new board and start writing reading saved
 // posts into it
 func InitializeBoard() *Board {
 	board := NewBoard([]Post{})
-	go board.populateBoardFromCSV()
+	loaded := make(chan struct{})
+	board.loaded = loaded
+	go func() {
+		board.populateBoardFromCSV()
+		close(loaded)
+	}()
+	return &board
+}
+
+// LoadBoard creates a new board from saved posts, waiting until all posts
+// are read
+func LoadBoard() *Board {
+	board := NewBoard([]Post{})
+	board.populateBoardFromCSV()
 	return &board
 }
 
 // NewBoard creates a Board from an array of Posts
 func NewBoard(p []Post) Board {
+	loaded := make(chan struct{})
+	close(loaded)
 	return Board{
-		Posts: p,
-		mut:   &sync.RWMutex{},
+		Posts:   p,
+		mut:     &sync.RWMutex{},
+		updated: time.Now(),
+		loaded:  loaded,
 	}
 }
 
 func (b *Board) populateBoardFromCSV() {
+	start := time.Now()
 	b.mut.Lock()
 	posts := ReadPostsFromCSV(getCSVPath(false))
+	attachImageMeta(posts, ReadImageMetaFromCSV(getImageCSVPath(false)))
 	b.Posts = append(b.Posts, posts...)
+	// Sort before changing the version so that results cached for the new
+	// version are never computed from unsorted posts
+	sort.Sort(sort.Reverse(SortByLikes(b.Posts)))
+	b.changed()
+	b.loadDuration = time.Since(start)
 	b.mut.Unlock()
-	b.SortPostsByLikes()
 }
 
 // AddPost adds a single post to the board and sorts it
@@ -117,6 +191,67 @@ func (b *Board) AddPost(p Post) {
 		}
 	}
 	b.Posts = append(b.Posts, p)
+	b.changed()
+}
+
+// RemovePost removes the post matching postID from the board and returns
+// whether it was found
+func (b *Board) RemovePost(postID int64) bool {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	for i := 0; i < len(b.Posts); i++ {
+		if b.Posts[i].ID == postID {
+			b.Posts = append(b.Posts[:i:i], b.Posts[i+1:]...)
+			b.changed()
+			return true
+		}
+	}
+	return false
+}
+
+// changed records that the posts of the board were added or removed, and
+// must be called with the lock held
+func (b *Board) changed() {
+	b.version++
+	b.updated = time.Now()
+}
+
+// Version returns a number that increases every time posts are added to or
+// removed from the board, so that data derived from the board can be cached
+func (b *Board) Version() int64 {
+	b.mut.RLock()
+	defer b.mut.RUnlock()
+	return b.version
+}
+
+// Updated returns the time that posts were last added to or removed from the
+// board
+func (b *Board) Updated() time.Time {
+	b.mut.RLock()
+	defer b.mut.RUnlock()
+	return b.updated
+}
+
+// Len returns the number of posts on the board
+func (b *Board) Len() int {
+	b.mut.RLock()
+	defer b.mut.RUnlock()
+	return len(b.Posts)
+}
+
+// Loaded returns a channel that is closed once saved posts have been read
+// into the board.  It is already closed for boards that do not read saved
+// posts.
+func (b *Board) Loaded() <-chan struct{} {
+	return b.loaded
+}
+
+// LoadDuration returns how long reading saved posts into the board took, or
+// 0 if they have not been read yet
+func (b *Board) LoadDuration() time.Duration {
+	b.mut.RLock()
+	defer b.mut.RUnlock()
+	return b.loadDuration
 }
 
 // PostsToJSON converts a Post into a JSON string
@@ -145,6 +280,39 @@ func (b Board) FilterBoard(query string) *Board {
 	return &board
 }
 
+// FilterBoardByImage returns a new Board with a subset of posts whose image
+// metadata matches the filter
+func (b Board) FilterBoardByImage(filter ImageFilter) *Board {
+	b.mut.RLock()
+	defer b.mut.RUnlock()
+	selectedPosts := []Post{}
+	for _, post := range b.Posts {
+		if filter.Match(post.Meta) {
+			selectedPosts = append(selectedPosts, post)
+		}
+	}
+	board := NewBoard(selectedPosts)
+	return &board
+}
+
+// ValidateImageFilter returns an error if the filter is invalid, or if it has
+// constraints and no post on the board has image metadata, which would make
+// every result empty
+func (b *Board) ValidateImageFilter(filter ImageFilter) error {
+	err := filter.Validate()
+	if err != nil || filter.Empty() {
+		return err
+	}
+	b.mut.RLock()
+	defer b.mut.RUnlock()
+	for _, post := range b.Posts {
+		if post.Meta != nil {
+			return nil
+		}
+	}
+	return ErrNotIndexed
+}
+
 // GetPostByID returns a post that matches the postID
 func (b Board) GetPostByID(postID int64) *Post {
 	b.mut.RLock()
@@ -183,6 +351,26 @@ func (a SortByLikes) Len() int           { return len(a) }
 func (a SortByLikes) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 func (a SortByLikes) Less(i, j int) bool { return a[i].Likes < a[j].Likes }
 
+// SortPostsByNewest will sort the current Board's posts by timestamp, then by
+// id for posts without timestamps, newest first
+func (b *Board) SortPostsByNewest() {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	sort.Sort(sort.Reverse(SortByNewest(b.Posts)))
+}
+
+// SortByNewest is an interface for Sorting
+type SortByNewest []Post
+
+func (a SortByNewest) Len() int      { return len(a) }
+func (a SortByNewest) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a SortByNewest) Less(i, j int) bool {
+	if a[i].Timestamp != a[j].Timestamp {
+		return a[i].Timestamp < a[j].Timestamp
+	}
+	return a[i].ID < a[j].ID
+}
+
 // RandomizePosts will shuffle the current Board's posts
 func (b *Board) RandomizePosts() {
 	b.mut.Lock()
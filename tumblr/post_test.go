@@ -1,6 +1,7 @@
 package tumblr
 
 import (
+	"sort"
 	"strconv"
 	"strings"
 	"testing"
@@ -11,11 +12,11 @@ import (
 
 func TestPost(t *testing.T) {
 	post := Post{
-		1234,
-		"title",
-		"url",
-		"https://img.reaction.pics/file/reaction-pics/abcd.gif",
-		123,
+		ID:    1234,
+		Title: "title",
+		URL:   "url",
+		Image: "https://img.reaction.pics/file/reaction-pics/abcd.gif",
+		Likes: 123,
 	}
 	assert.Equal(t, post.ID, int64(1234))
 	assert.Equal(t, post.Title, "title")
@@ -46,13 +47,13 @@ func TestCorruptPost(t *testing.T) {
 }
 
 func TestInternalURL(t *testing.T) {
-	post := Post{1, "title1", "url1", "https://img.reaction.pics/file/reaction-pics/abcd.gif", 123}
+	post := Post{ID: 1, Title: "title1", URL: "url1", Image: "https://img.reaction.pics/file/reaction-pics/abcd.gif", Likes: 123}
 	url := post.InternalURL()
 	assert.Equal(t, url, "/post/1/title1")
 }
 
 func TestInternalURLLong(t *testing.T) {
-	post := Post{1, strings.Repeat("a", 50), "url1", "https://img.reaction.pics/file/reaction-pics/abcd.gif", 123}
+	post := Post{ID: 1, Title: strings.Repeat("a", 50), URL: "url1", Image: "https://img.reaction.pics/file/reaction-pics/abcd.gif", Likes: 123}
 	url := post.InternalURL()
 	assert.Equal(t, url, "/post/1/"+strings.Repeat("a", 30))
 }
@@ -67,7 +68,7 @@ func TestInitializeBoard(t *testing.T) {
 }
 
 func TestAddPost(t *testing.T) {
-	post := Post{1, "title1", "url1", "https://img.reaction.pics/file/reaction-pics/abcd.gif", 123}
+	post := Post{ID: 1, Title: "title1", URL: "url1", Image: "https://img.reaction.pics/file/reaction-pics/abcd.gif", Likes: 123}
 	board := NewBoard([]Post{})
 	board.AddPost(post)
 	assert.Equal(t, len(board.Posts), 1)
@@ -79,8 +80,8 @@ func TestAddPost(t *testing.T) {
 
 func TestPostsToJSON(t *testing.T) {
 	posts := make([]Post, 2)
-	posts[0] = Post{1, "title1", "url1", "https://img.reaction.pics/file/reaction-pics/abcd.gif", 123}
-	posts[1] = Post{2, "title2", "url2", "https://img.reaction.pics/file/reaction-pics/abcd.gif", 124}
+	posts[0] = Post{ID: 1, Title: "title1", URL: "url1", Image: "https://img.reaction.pics/file/reaction-pics/abcd.gif", Likes: 123}
+	posts[1] = Post{ID: 2, Title: "title2", URL: "url2", Image: "https://img.reaction.pics/file/reaction-pics/abcd.gif", Likes: 124}
 	board := NewBoard(posts)
 	data := board.PostsToJSON()
 	assert.Equal(t, len(*data), 2)
@@ -96,8 +97,8 @@ func TestPostsToJSON(t *testing.T) {
 
 func TestFilterBoard(t *testing.T) {
 	posts := make([]Post, 2)
-	posts[0] = Post{1, "title1", "url1", "https://img.reaction.pics/file/reaction-pics/abcd.gif", 123}
-	posts[1] = Post{2, "title2", "url2", "https://img.reaction.pics/file/reaction-pics/abcd.gif", 124}
+	posts[0] = Post{ID: 1, Title: "title1", URL: "url1", Image: "https://img.reaction.pics/file/reaction-pics/abcd.gif", Likes: 123}
+	posts[1] = Post{ID: 2, Title: "title2", URL: "url2", Image: "https://img.reaction.pics/file/reaction-pics/abcd.gif", Likes: 124}
 	board := NewBoard(posts)
 	newBoard := board.FilterBoard("title2")
 	assert.Equal(t, len(newBoard.Posts), 1)
@@ -106,8 +107,8 @@ func TestFilterBoard(t *testing.T) {
 
 func TestLimitBoard(t *testing.T) {
 	posts := make([]Post, 2)
-	posts[0] = Post{1, "title1", "url1", "https://img.reaction.pics/file/reaction-pics/abcd.gif", 123}
-	posts[1] = Post{2, "title2", "url2", "https://img.reaction.pics/file/reaction-pics/abcd.gif", 124}
+	posts[0] = Post{ID: 1, Title: "title1", URL: "url1", Image: "https://img.reaction.pics/file/reaction-pics/abcd.gif", Likes: 123}
+	posts[1] = Post{ID: 2, Title: "title2", URL: "url2", Image: "https://img.reaction.pics/file/reaction-pics/abcd.gif", Likes: 124}
 	board := NewBoard(posts)
 	board.LimitBoard(1, 1)
 	assert.Equal(t, len(board.Posts), 1)
@@ -122,20 +123,33 @@ func TestLimitBoard(t *testing.T) {
 
 func TestSortPostsByLikes(t *testing.T) {
 	board := NewBoard([]Post{})
-	board.AddPost(Post{3, "title3", "url3", "https://img.reaction.pics/file/reaction-pics/abcd.gif", 123})
-	board.AddPost(Post{1, "title1", "url1", "https://img.reaction.pics/file/reaction-pics/abcd.gif", 121})
-	board.AddPost(Post{2, "title2", "url2", "https://img.reaction.pics/file/reaction-pics/abcd.gif", 122})
+	board.AddPost(Post{ID: 3, Title: "title3", URL: "url3", Image: "https://img.reaction.pics/file/reaction-pics/abcd.gif", Likes: 123})
+	board.AddPost(Post{ID: 1, Title: "title1", URL: "url1", Image: "https://img.reaction.pics/file/reaction-pics/abcd.gif", Likes: 121})
+	board.AddPost(Post{ID: 2, Title: "title2", URL: "url2", Image: "https://img.reaction.pics/file/reaction-pics/abcd.gif", Likes: 122})
 	board.SortPostsByLikes()
 	assert.Equal(t, board.Posts[0].Likes, int64(123))
 	assert.Equal(t, board.Posts[1].Likes, int64(122))
 	assert.Equal(t, board.Posts[2].Likes, int64(121))
 }
 
+func TestSortPostsByNewest(t *testing.T) {
+	board := NewBoard([]Post{})
+	board.AddPost(Post{ID: 3, Title: "title3", Timestamp: 1500000000})
+	board.AddPost(Post{ID: 1, Title: "title1"})
+	board.AddPost(Post{ID: 2, Title: "title2"})
+	board.AddPost(Post{ID: 4, Title: "title4", Timestamp: 1400000000})
+	board.SortPostsByNewest()
+	assert.Equal(t, board.Posts[0].ID, int64(3))
+	assert.Equal(t, board.Posts[1].ID, int64(4))
+	assert.Equal(t, board.Posts[2].ID, int64(2))
+	assert.Equal(t, board.Posts[3].ID, int64(1))
+}
+
 func TestRandomizePosts(t *testing.T) {
 	board := NewBoard([]Post{})
-	board.AddPost(Post{1, "title1", "url1", "https://img.reaction.pics/file/reaction-pics/abcd.gif", 121})
-	board.AddPost(Post{2, "title2", "url2", "https://img.reaction.pics/file/reaction-pics/abcd.gif", 122})
-	board.AddPost(Post{3, "title3", "url3", "https://img.reaction.pics/file/reaction-pics/abcd.gif", 123})
+	board.AddPost(Post{ID: 1, Title: "title1", URL: "url1", Image: "https://img.reaction.pics/file/reaction-pics/abcd.gif", Likes: 121})
+	board.AddPost(Post{ID: 2, Title: "title2", URL: "url2", Image: "https://img.reaction.pics/file/reaction-pics/abcd.gif", Likes: 122})
+	board.AddPost(Post{ID: 3, Title: "title3", URL: "url3", Image: "https://img.reaction.pics/file/reaction-pics/abcd.gif", Likes: 123})
 	randomized := false
 	for i := 0; i < 10; i++ {
 		// Technically a flaky test, but is expected to only fail in one out of 3^10 chances
@@ -151,9 +165,9 @@ func TestRandomizePosts(t *testing.T) {
 
 func TestURLs(t *testing.T) {
 	board := NewBoard([]Post{})
-	board.AddPost(Post{3, "title3", "url3", "https://img.reaction.pics/file/reaction-pics/abcd.gif", 123})
-	board.AddPost(Post{1, "title1", "url1", "https://img.reaction.pics/file/reaction-pics/abcd.gif", 121})
-	board.AddPost(Post{2, "title2", "url2", "https://img.reaction.pics/file/reaction-pics/abcd.gif", 122})
+	board.AddPost(Post{ID: 3, Title: "title3", URL: "url3", Image: "https://img.reaction.pics/file/reaction-pics/abcd.gif", Likes: 123})
+	board.AddPost(Post{ID: 1, Title: "title1", URL: "url1", Image: "https://img.reaction.pics/file/reaction-pics/abcd.gif", Likes: 121})
+	board.AddPost(Post{ID: 2, Title: "title2", URL: "url2", Image: "https://img.reaction.pics/file/reaction-pics/abcd.gif", Likes: 122})
 	urls := board.URLs()
 	assert.Equal(t, len(urls), 3)
 	assert.Equal(t, urls[0], "/post/3/title3")
@@ -163,9 +177,9 @@ func TestURLs(t *testing.T) {
 
 func TestKeywords(t *testing.T) {
 	board := NewBoard([]Post{})
-	board.AddPost(Post{3, "title2", "url3", "https://img.reaction.pics/file/reaction-pics/abcd.gif", 123})
-	board.AddPost(Post{1, "title1", "url1", "https://img.reaction.pics/file/reaction-pics/abcd.gif", 121})
-	board.AddPost(Post{2, "title1 title2 title2", "url2", "https://img.reaction.pics/file/reaction-pics/abcd.gif", 122})
+	board.AddPost(Post{ID: 3, Title: "title2", URL: "url3", Image: "https://img.reaction.pics/file/reaction-pics/abcd.gif", Likes: 123})
+	board.AddPost(Post{ID: 1, Title: "title1", URL: "url1", Image: "https://img.reaction.pics/file/reaction-pics/abcd.gif", Likes: 121})
+	board.AddPost(Post{ID: 2, Title: "title1 title2 title2", URL: "url2", Image: "https://img.reaction.pics/file/reaction-pics/abcd.gif", Likes: 122})
 	keywords := board.Keywords()
 	assert.Equal(t, len(keywords), 2)
 	assert.Equal(t, keywords[0], "title2")
@@ -178,7 +192,92 @@ func TestKeywordsLong(t *testing.T) {
 	for x := 10000; x < 10100; x++ {
 		title = append(title, strconv.FormatInt(int64(x), 10))
 	}
-	board.AddPost(Post{1, strings.Join(title, " "), "url1", "https://img.reaction.pics/file/reaction-pics/abcd.gif", 121})
+	board.AddPost(Post{ID: 1, Title: strings.Join(title, " "), URL: "url1", Image: "https://img.reaction.pics/file/reaction-pics/abcd.gif", Likes: 121})
 	keywords := board.Keywords()
 	assert.Equal(t, len(keywords), MaxKeywords)
 }
+
+func TestFilterBoardByImage(t *testing.T) {
+	posts := make([]Post, 3)
+	posts[0] = Post{ID: 1, Meta: &ImageMeta{Frames: 1}}
+	posts[1] = Post{ID: 2, Meta: &ImageMeta{Frames: 20, Duration: 2000}}
+	posts[2] = Post{ID: 3}
+	board := NewBoard(posts)
+	newBoard := board.FilterBoardByImage(ImageFilter{Type: ImageTypeStatic})
+	assert.Equal(t, len(newBoard.Posts), 1)
+	assert.Equal(t, newBoard.Posts[0].ID, int64(1))
+
+	newBoard = board.FilterBoardByImage(ImageFilter{})
+	assert.Equal(t, len(newBoard.Posts), 3)
+}
+
+func TestRemovePost(t *testing.T) {
+	board := NewBoard([]Post{})
+	board.AddPost(Post{ID: 1, Title: "title1"})
+	board.AddPost(Post{ID: 2, Title: "title2"})
+	assert.True(t, board.RemovePost(1))
+	assert.Equal(t, len(board.Posts), 1)
+	assert.Equal(t, board.Posts[0].ID, int64(2))
+	assert.False(t, board.RemovePost(1))
+}
+
+func TestValidateImageFilter(t *testing.T) {
+	board := NewBoard([]Post{{ID: 1, Title: "unindexed"}})
+	assert.NoError(t, board.ValidateImageFilter(ImageFilter{}))
+	assert.Equal(t, board.ValidateImageFilter(ImageFilter{Type: ImageTypeStatic}), ErrNotIndexed)
+	assert.Error(t, board.ValidateImageFilter(ImageFilter{MaxDuration: -1}))
+
+	board.AddPost(Post{ID: 2, Title: "indexed", Meta: &ImageMeta{Frames: 1}})
+	assert.NoError(t, board.ValidateImageFilter(ImageFilter{Type: ImageTypeStatic}))
+}
+
+func TestBoardVersion(t *testing.T) {
+	board := NewBoard([]Post{})
+	assert.Equal(t, board.Version(), int64(0))
+	updated := board.Updated()
+	board.AddPost(Post{ID: 1, Title: "title1"})
+	assert.Equal(t, board.Version(), int64(1))
+	assert.False(t, board.Updated().Before(updated))
+	board.AddPost(Post{ID: 1, Title: "title1"})
+	assert.Equal(t, board.Version(), int64(1))
+	board.SortPostsByLikes()
+	assert.Equal(t, board.Version(), int64(1))
+	board.RemovePost(2)
+	assert.Equal(t, board.Version(), int64(1))
+	board.RemovePost(1)
+	assert.Equal(t, board.Version(), int64(2))
+}
+
+func TestBoardLen(t *testing.T) {
+	board := NewBoard([]Post{})
+	assert.Equal(t, board.Len(), 0)
+	board.AddPost(Post{ID: 1, Title: "title1"})
+	assert.Equal(t, board.Len(), 1)
+}
+
+func TestImageURL(t *testing.T) {
+	assert.Equal(t, ImageURL("abcd.gif"), "https://img.reaction.pics/file/reaction-pics/abcd.gif")
+}
+
+func TestLoadBoard(t *testing.T) {
+	b := LoadBoard()
+	assert.True(t, len(b.Posts) > 0)
+	assert.True(t, sort.IsSorted(sort.Reverse(SortByLikes(b.Posts))))
+	assert.True(t, b.LoadDuration() > 0)
+	empty := NewBoard([]Post{})
+	assert.Equal(t, empty.LoadDuration(), time.Duration(0))
+}
+
+func TestBoardLoaded(t *testing.T) {
+	b := InitializeBoard()
+	<-b.Loaded()
+	assert.True(t, b.Len() > 0)
+	assert.True(t, b.LoadDuration() > 0)
+
+	empty := NewBoard([]Post{})
+	select {
+	case <-empty.Loaded():
+	default:
+		assert.Fail(t, "new boards should be loaded")
+	}
+}
@@ -0,0 +1,74 @@
+package tumblr
+
+import (
+	"sort"
+	"strings"
+)
+
+// Search returns a page of up to limit posts starting at offset that match
+// a query and image filter, along with the total number of matching posts.
+// An empty query matches all posts in random order, and posts within the
+// page are sorted by likes.
+func (b Board) Search(query string, filter ImageFilter, offset, limit int) (*Board, int) {
+	query = strings.ToLower(query)
+	queriedBoard := b.FilterBoard(query)
+	if !filter.Empty() {
+		queriedBoard = queriedBoard.FilterBoardByImage(filter)
+	}
+	if query == "" {
+		queriedBoard.RandomizePosts()
+	}
+	total := len(queriedBoard.Posts)
+	queriedBoard.LimitBoard(offset, limit)
+	queriedBoard.SortPostsByLikes()
+	return queriedBoard, total
+}
+
+// relatedTerms returns the title keywords and tags of a post, using the same
+// keyword rule as Keywords
+func relatedTerms(post Post) map[string]int {
+	terms := map[string]int{}
+	for _, word := range strings.Fields(strings.ToLower(post.Title)) {
+		if len(word) > 4 {
+			terms[word] = 1
+		}
+	}
+	for _, tag := range post.Tags {
+		terms["tag:"+strings.ToLower(tag)] = 2
+	}
+	return terms
+}
+
+// RelatedPosts returns up to limit other posts that share title keywords or
+// tags with a post, ranked by the number of shared terms (tags count double)
+// and then by likes
+func (b Board) RelatedPosts(post Post, limit int) []Post {
+	terms := relatedTerms(post)
+	b.mut.RLock()
+	related := []Post{}
+	scores := map[int64]int{}
+	for _, other := range b.Posts {
+		if other.ID == post.ID {
+			continue
+		}
+		score := 0
+		for term := range relatedTerms(other) {
+			score += terms[term]
+		}
+		if score > 0 {
+			related = append(related, other)
+			scores[other.ID] = score
+		}
+	}
+	b.mut.RUnlock()
+	sort.SliceStable(related, func(i, j int) bool {
+		if scores[related[i].ID] != scores[related[j].ID] {
+			return scores[related[i].ID] > scores[related[j].ID]
+		}
+		return related[i].Likes > related[j].Likes
+	})
+	if len(related) > limit {
+		related = related[:limit]
+	}
+	return related
+}
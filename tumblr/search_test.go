@@ -0,0 +1,50 @@
+package tumblr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearch(t *testing.T) {
+	board := NewBoard([]Post{})
+	board.AddPost(Post{ID: 1, Title: "Deploy one", Likes: 1, Meta: &ImageMeta{Frames: 1}})
+	board.AddPost(Post{ID: 2, Title: "deploy two", Likes: 3, Meta: &ImageMeta{Frames: 5}})
+	board.AddPost(Post{ID: 3, Title: "deploy three", Likes: 2, Meta: &ImageMeta{Frames: 5}})
+	board.AddPost(Post{ID: 4, Title: "outage", Likes: 4})
+
+	page, total := board.Search("DEPLOY", ImageFilter{}, 0, 2)
+	assert.Equal(t, total, 3)
+	assert.Equal(t, len(page.Posts), 2)
+	assert.Equal(t, page.Posts[0].ID, int64(2))
+	assert.Equal(t, page.Posts[1].ID, int64(1))
+
+	page, total = board.Search("deploy", ImageFilter{Type: ImageTypeAnimated}, 1, 2)
+	assert.Equal(t, total, 2)
+	assert.Equal(t, len(page.Posts), 1)
+	assert.Equal(t, page.Posts[0].ID, int64(3))
+
+	page, total = board.Search("", ImageFilter{}, 0, 10)
+	assert.Equal(t, total, 4)
+	assert.Equal(t, page.Posts[0].ID, int64(4))
+	assert.Equal(t, len(board.Posts), 4)
+}
+
+func TestRelatedPosts(t *testing.T) {
+	board := NewBoard([]Post{})
+	board.AddPost(Post{ID: 1, Title: "When the deploy fails", Tags: []string{"ops"}})
+	board.AddPost(Post{ID: 2, Title: "When the deploy works", Likes: 1})
+	board.AddPost(Post{ID: 3, Title: "Friday deploy", Likes: 5})
+	board.AddPost(Post{ID: 4, Title: "On call", Tags: []string{"OPS"}})
+	board.AddPost(Post{ID: 5, Title: "Unrelated"})
+
+	related := board.RelatedPosts(*board.GetPostByID(1), 10)
+	ids := []int64{}
+	for _, post := range related {
+		ids = append(ids, post.ID)
+	}
+	assert.Equal(t, ids, []int64{4, 3, 2})
+
+	related = board.RelatedPosts(*board.GetPostByID(1), 1)
+	assert.Equal(t, len(related), 1)
+}
@@ -0,0 +1,50 @@
+package tumblr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Problem is an issue with the data of a saved post
+type Problem struct {
+	PostID  int64
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%d: %s", p.PostID, p.Message)
+}
+
+// ValidatePosts checks posts for data that would make them unsearchable or
+// unreachable
+func ValidatePosts(posts []Post) []Problem {
+	problems := []Problem{}
+	ids := map[int64]bool{}
+	titles := map[string]int64{}
+	for _, post := range posts {
+		add := func(format string, args ...interface{}) {
+			problems = append(problems, Problem{PostID: post.ID, Message: fmt.Sprintf(format, args...)})
+		}
+		if post.ID <= 0 {
+			add("invalid id")
+		} else if ids[post.ID] {
+			add("duplicate id")
+		}
+		ids[post.ID] = true
+		title := strings.TrimSpace(post.Title)
+		if title == "" {
+			add("missing title")
+		} else if id, ok := titles[title]; ok {
+			add("duplicate title of post %d", id)
+		} else {
+			titles[title] = post.ID
+		}
+		if post.Image == "" || post.Image == imageRootPath {
+			add("missing image")
+		}
+		if post.Likes < 0 {
+			add("negative likes")
+		}
+	}
+	return problems
+}
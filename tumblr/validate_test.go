@@ -0,0 +1,34 @@
+package tumblr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatePosts(t *testing.T) {
+	posts := []Post{
+		{ID: 1, Title: "title1", Image: ImageURL("a.gif")},
+		{ID: 1, Title: "title2", Image: ImageURL("b.gif")},
+		{ID: 2, Title: "title1", Image: ImageURL("c.gif")},
+		{ID: 0, Title: " ", Image: ImageURL(""), Likes: -1},
+	}
+	problems := ValidatePosts(posts)
+	messages := []string{}
+	for _, problem := range problems {
+		messages = append(messages, problem.String())
+	}
+	assert.Equal(t, messages, []string{
+		"1: duplicate id",
+		"2: duplicate title of post 1",
+		"0: invalid id",
+		"0: missing title",
+		"0: missing image",
+		"0: negative likes",
+	})
+}
+
+func TestValidateSavedPosts(t *testing.T) {
+	posts := ReadPostsFromCSV(getCSVPath(true))
+	assert.Equal(t, len(ValidatePosts(posts)), 0)
+}